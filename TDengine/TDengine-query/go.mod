@@ -1,16 +0,0 @@
-module performance_testing/TDengine/TDengine-query
-
-go 1.20
-
-require github.com/taosdata/driver-go/v3 v3.5.3
-
-require (
-	github.com/astaxie/beego v1.12.3 // indirect
-	github.com/go-sql-driver/mysql v1.5.0 // indirect
-	github.com/google/uuid v1.3.0 // indirect
-	github.com/gorilla/websocket v1.5.0 // indirect
-	github.com/shiena/ansicolor v0.0.0-20151119151921-a422bbe96644 // indirect
-	performance_testing/common v0.0.0-00010101000000-000000000000 // indirect
-)
-
-replace performance_testing/common => ../../common
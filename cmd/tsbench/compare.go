@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"performance_testing/common"
+)
+
+// runCompare 对比两次测试结果, 有指标回退超过阈值时返回错误, 进程以非 0 退出, 可用于 CI 检查
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	history := fs.String("history", "history", "Directory of the run history written by '-history'.")
+	threshold := fs.Float64("threshold", common.DefaultRegressionThreshold, "Regression threshold in percent, a metric worse than the base by more than it fails the comparison.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 2 {
+		err := errors.New("usage: tsbench compare [-history history -threshold 10] [base [current]]")
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	baseRef, curRef := "previous", "latest"
+	if fs.NArg() == 2 {
+		baseRef, curRef = fs.Arg(0), fs.Arg(1)
+	} else if fs.NArg() == 1 {
+		baseRef = fs.Arg(0)
+	}
+
+	// 本次结果取自历史记录时, 基线在它之前的同一数据库、同一子命令的结果中查找
+	var results []*common.Result
+	load := func(ref string, like *common.Result) (error, *common.Result) {
+		if _, err := os.Stat(ref); err == nil {
+			return common.LoadResult(ref)
+		}
+		if results == nil {
+			err, all := common.LoadHistory(*history)
+			if err != nil {
+				return err, nil
+			}
+			results = all
+		}
+		return common.FindRun(results, ref, like)
+	}
+	err, cur := load(curRef, nil)
+	if err != nil {
+		return err
+	}
+	err, base := load(baseRef, cur)
+	if err != nil {
+		return err
+	}
+	if base.Target != cur.Target || base.Command != cur.Command {
+		fmt.Printf("warning: comparing %s %s with %s %s\n", base.Target, base.Command, cur.Target, cur.Command)
+	}
+
+	comps := common.CompareResults(base, cur, *threshold)
+	if regressions := common.PrintComparisons(base, cur, comps, *threshold); regressions > 0 {
+		return errors.New(fmt.Sprintf("%d regressions beyond %.2f%%", regressions, *threshold))
+	}
+	return nil
+}
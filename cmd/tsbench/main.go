@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"performance_testing/common"
+)
+
+const usage = `usage: tsbench <command> [arguments]
+
+commands:
+  write    --target mo|ck|td|influx|sr [-T 7 -r 10000 -n 500000 -retry 1 -mode multi -txc 0 -tType ts -wType loadLine]
+           [-duration 1h -interval 10s -rate 0 -arrival closed|fixed|poisson -onError fail|continue|retry -writeRetries 3]
+           [-pause 1s -preRound 'sync; echo 3 > /proc/sys/vm/drop_caches' -interactive]
+           [-schema schema.yaml -devices 100000 -seed 42 -gen 'current=walk:step=0.1']
+           [-json result.json -csv result.csv -history history -label nightly-20240101]
+  query    --target mo|ck|td|influx|sr [-T 1 -iterations 1 -warmup 0 -concurrent -qps 100]
+           [-json result.json -csv result.csv -history history -label nightly-20240101]
+  saturate --target mo|ck|td|influx|sr [-slo 100ms -search step|binary -rateStart 10000 -rateStep 10000 -rateMax 0 -precision 0.05]
+           [write arguments]
+  mixed    --target mo|ck|td|influx|sr [-readers 1 -mix 'point=4,avg=1,timeWindow=1,latest=4' -qps 0] [write arguments]
+  sweep    --target mo|ck|td|influx|sr -sweep 'r=1000,10000;T=1..16:x2' [-sweepMode cartesian|oat] [write arguments]
+  run      scenario.yaml
+  compare  [-history history -threshold 10] [base [current]]
+           base and current are result json files, run ids, labels, 'latest' or 'previous', default previous and latest
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+		os.Exit(1)
+	}
+}
+
+// runCommand 执行一个子命令, run 子命令中的每一步也通过它执行
+func runCommand(cmd string, args []string) error {
+	switch cmd {
+	case "write":
+		err, _ := runWrite(args)
+		return err
+	case "query":
+		err, _ := runQuery(args)
+		return err
+	case "saturate":
+		err, _ := runSaturate(args)
+		return err
+	case "mixed":
+		err, _ := runMixed(args)
+		return err
+	case "run":
+		return runScenario(args)
+	case "sweep":
+		return runSweep(args)
+	case "compare":
+		return runCompare(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Printf("unknown command:%s\n%s", cmd, usage)
+		return fmt.Errorf("unknown command:%s", cmd)
+	}
+}
+
+func runWrite(args []string) (error, *common.Result) {
+	o, err := parseOptions("write", args)
+	if err != nil {
+		return err, nil
+	}
+
+	err, p := o.writeParams()
+	if err != nil {
+		return err, nil
+	}
+	fmt.Printf("target=%s, r=%s, T=%s, n=%s, mode=%s, retry=%s, txc=%s, tType=%s, wType=%s, t=%s, devices=%d, duration=%v \n",
+		o.target, o.r, o.T, o.n, o.mode, o.retry, o.txc, o.tType, o.wType, o.t, o.schema.Devices, o.duration)
+
+	err, b := newBackend(o)
+	if err != nil {
+		return err, nil
+	}
+	err, result := common.RunWrite(b, p)
+	if outErr := o.writeResult("write", result); err == nil {
+		err = outErr
+	}
+	return err, result
+}
+
+func runQuery(args []string) (error, *common.Result) {
+	o, err := parseOptions("query", args)
+	if err != nil {
+		return err, nil
+	}
+
+	err, p := o.queryParams()
+	if err != nil {
+		return err, nil
+	}
+	fmt.Printf("target=%s, T=%d, iterations=%d, warmup=%d, concurrent=%v, qps=%g \n",
+		o.target, p.T, p.Iterations, p.Warmup, p.Concurrent, p.QPS)
+
+	err, b := newBackend(o)
+	if err != nil {
+		return err, nil
+	}
+	err, result := common.RunQuery(b, p)
+	if err == nil && o.expect != "" {
+		var expected *common.Result
+		if err, expected = common.LoadResult(o.expect); err == nil {
+			err = common.CheckQueries(result, expected.Expected)
+			common.PrintCorrectness([]*common.Result{result})
+		}
+	}
+	if outErr := o.writeResult("query", result); err == nil {
+		err = outErr
+	}
+	return err, result
+}
+
+func runSaturate(args []string) (error, *common.Result) {
+	o, err := parseOptions("saturate", args)
+	if err != nil {
+		return err, nil
+	}
+
+	err, p := o.writeParams()
+	if err != nil {
+		return err, nil
+	}
+	fmt.Printf("target=%s, r=%s, T=%s, n=%s, mode=%s, txc=%s, tType=%s, wType=%s, t=%s, devices=%d, slo=%v, search=%s, rateStart=%g, rateStep=%g, rateMax=%g \n",
+		o.target, o.r, o.T, o.n, o.mode, o.txc, o.tType, o.wType, o.t, o.schema.Devices, o.slo, o.search, o.rateStart, o.rateStep, o.rateMax)
+
+	err, b := newBackend(o)
+	if err != nil {
+		return err, nil
+	}
+	err, result := common.RunSaturation(b, p, o.saturationParams())
+	if outErr := o.writeResult("saturate", result); err == nil {
+		err = outErr
+	}
+	return err, result
+}
+
+func runMixed(args []string) (error, *common.Result) {
+	o, err := parseOptions("mixed", args)
+	if err != nil {
+		return err, nil
+	}
+
+	err, p := o.writeParams()
+	if err != nil {
+		return err, nil
+	}
+	err, m := o.mixedParams()
+	if err != nil {
+		return err, nil
+	}
+	fmt.Printf("target=%s, r=%s, T=%s, n=%s, mode=%s, retry=%s, txc=%s, tType=%s, wType=%s, t=%s, devices=%d, duration=%v, readers=%d, mix=%s, qps=%g \n",
+		o.target, o.r, o.T, o.n, o.mode, o.retry, o.txc, o.tType, o.wType, o.t, o.schema.Devices, o.duration, o.readers, o.mix, o.qps)
+
+	err, b := newBackend(o)
+	if err != nil {
+		return err, nil
+	}
+	err, result := common.RunMixed(b, p, m)
+	if outErr := o.writeResult("mixed", result); err == nil {
+		err = outErr
+	}
+	return err, result
+}
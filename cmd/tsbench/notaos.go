@@ -0,0 +1,5 @@
+//go:build !taos
+
+package main
+
+const taosSupported = false
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"performance_testing/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// options 子命令参数, 沿用原来各个工具的参数名
+type options struct {
+	target string
+	conf   string
+
+	T, r, n, retry, mode, txc, tType, wType, t string
+
+	interactive bool
+	pause       time.Duration
+	preRound    string
+	queue       int
+	seed        int64
+	gen         string
+	schemaFile  string
+	devices     int
+	iterations  int
+	warmup      int
+	concurrent  bool
+	qps         float64
+	jsonFile    string
+	csvFile     string
+	history     string
+	label       string
+	phase       string
+	sweep       string
+	sweepMode   string
+	rate        float64
+	arrival     string
+	onError     string
+	retries     int
+	retryDelay  time.Duration
+	retryMax    time.Duration
+	retryJitter float64
+	verify      string
+	check       bool
+	expect      string
+	duration    time.Duration
+	interval    time.Duration
+	slo         time.Duration
+	search      string
+	rateStart   float64
+	rateStep    float64
+	rateMax     float64
+	precision   float64
+	readers     int
+	mix         string
+
+	schema *common.Schema // 由 schemaFile 读取, 未指定时为默认表结构
+}
+
+// targetInfo 被测数据库的配置目录和原来各个工具的默认参数
+type targetInfo struct {
+	name     string // common 中的数据库名称
+	confDir  string
+	defaults map[string]string
+}
+
+var targets = map[string]targetInfo{
+	"mo":     {common.MO, "matrixone", map[string]string{"r": "10000", "T": "7", "n": "500000"}},
+	"ck":     {common.CK, "clickhouse", map[string]string{"r": "10000", "T": "7", "n": "500000"}},
+	"td":     {common.TDengine, "TDengine", map[string]string{"r": "10000", "T": "7", "n": "10000", "wType": common.Sql}},
+	"influx": {common.InfluxDB, "influxDB", map[string]string{"r": "10000", "T": "7", "n": "200000"}},
+	"sr":     {common.SR, "starrocks", map[string]string{"r": "100000", "T": "1", "n": "100000"}},
+}
+
+var targetAliases = map[string]string{
+	"matrixone":  "mo",
+	"clickhouse": "ck",
+	"tdengine":   "td",
+	"taos":       "td",
+	"influxdb":   "influx",
+	"starrocks":  "sr",
+}
+
+func parseOptions(cmd string, args []string) (*options, error) {
+	o := &options{}
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.StringVar(&o.target, "target", "", "The database to test, value is mo|ck|td|influx|sr.")
+	fs.StringVar(&o.conf, "conf", "", "The config file, default is <database dir>/conf/db.conf, e.g. matrixone/conf/db.conf.")
+	fs.StringVar(&o.r, "r", "10000", "The number of records per request. By default is 10000.")
+	fs.StringVar(&o.T, "T", "7", " The number of threads. By default use 7, query default 1")
+	fs.StringVar(&o.n, "n", "500000", "Number of records for each table, default is 500000")
+	fs.StringVar(&o.retry, "retry", "1", "Test retry count, calculate the average value finally, default 1")
+	fs.StringVar(&o.mode, "mode", "multi", "Import mode, value is multi|single, multi table import or single table import, default multi.")
+	fs.StringVar(&o.txc, "txc", "0", "The number of writes committed per transaction. 0 means not opening transactions. default 0.")
+	fs.StringVar(&o.tType, "tType", "ts", "default ts, ts|tsPK|intPK, ts: time series table without primary key.")
+	fs.StringVar(&o.wType, "wType", "loadLine", "insert|loadLine|loadFile|prepare, default loadLine, insert: write data by 'insert into values', loadLine: write data through 'load data INLINE', loadFile: write data through 'load data INFILE', "+
+		"prepare: server-side prepared multi-row 'insert into values' with bound parameters, split to stay under 65535 placeholders per statement. "+
+		"For td: sql|stmt|line|telnet, default sql, stmt: parameter binding, line: InfluxDB line protocol, telnet: OpenTSDB telnet protocol, all but sql require '-tags taos'.")
+	fs.StringVar(&o.t, "t", "1000", "Number of tables written by TDengine in multi mode, split across threads and n records for each table, default is 1000")
+	fs.BoolVar(&o.interactive, "interactive", false, "Wait for confirmation before each test round, only works when stdin is a terminal. default false.")
+	fs.DurationVar(&o.pause, "pause", time.Second, "Pause between test rounds, after the tables are truncated. default 1s.")
+	fs.StringVar(&o.preRound, "preRound", "", "Command run by bash before each test round, e.g. flush caches or compact. A failing command stops the test.")
+	fs.IntVar(&o.queue, "queue", common.DefaultQueueDepth, "Depth of the queue between the data generator and the writer of each thread, in requests.")
+	fs.Int64Var(&o.seed, "seed", common.DefaultSeed, "Random seed of the data generator, the same seed produces identical data for every database.")
+	fs.StringVar(&o.gen, "gen", "", "Value model of each column, column=model:k=v,...;... e.g. 'current=sine:amp=3,period=1440,noise=0.1;voltage=counter:max=19'. "+
+		"Models: uniform(min,max), walk(start,step,min,max), sine(base,amp,period,noise), step(start,delta,every), constant(value), counter(start,inc,max).")
+	fs.StringVar(&o.schemaFile, "schema", "", "Yaml file of the table schema: data columns (name, type int|bigint|float|double|bool|string, gen, nullable) and optional tag columns. "+
+		"Default is (ts, current float, voltage int, phase float).")
+	fs.IntVar(&o.devices, "devices", 0, "Number of devices (series), overrides 'devices' of the schema file. 0 means one table per thread as before. "+
+		"Each database uses its idiomatic layout: a table per device (td), a device_id tag (influx) or a wide table with a device_id column (mo|ck|sr).")
+	fs.IntVar(&o.iterations, "iterations", 1, "Query: number of measured runs of each query, reports min/mean/median/p95/p99/stddev when greater than 1. default 1.")
+	fs.IntVar(&o.warmup, "warmup", 0, "Query: number of unmeasured runs of each query before measuring. default 0.")
+	fs.BoolVar(&o.concurrent, "concurrent", false, "Query: run every query from T clients concurrently, each client runs it 'iterations' times. By default only 'select *' is concurrent.")
+	fs.Float64Var(&o.qps, "qps", 0, "Query and mixed: target total queries per second of all clients in concurrent mode, 0 means unlimited. default 0.")
+	fs.StringVar(&o.jsonFile, "json", "", "Write the result (target, version, parameters, per-round throughput, latency percentiles, errors) to this file as json.")
+	fs.StringVar(&o.csvFile, "csv", "", "Write the result to this file as flat csv, one row per round or query.")
+	fs.StringVar(&o.history, "history", "", "Directory of the run history, the result is appended to <dir>/history.jsonl for 'tsbench compare'.")
+	fs.StringVar(&o.label, "label", "", "Label of this run saved in the result, e.g. the build version, can be used as the baseline of 'tsbench compare'.")
+	fs.StringVar(&o.phase, "phase", "", "Name of the scenario phase saved in the result, set by 'tsbench run'.")
+	fs.StringVar(&o.sweep, "sweep", "", "Sweep: write parameters and their values, e.g. 'r=1000,10000,100000;T=1..16:x2;txc=0..100:20'. "+
+		"A range is start..end with step 1, start..end:step or start..end:xN.")
+	fs.StringVar(&o.sweepMode, "sweepMode", common.SweepCartesian, "Sweep: cartesian|oat, cartesian runs every combination, oat (one at a time) varies one parameter while the others keep their values.")
+	fs.Float64Var(&o.rate, "rate", 0, "Write: target total records per second of all threads, requests are sent on a fixed schedule. 0 means as fast as possible. default 0.")
+	fs.StringVar(&o.arrival, "arrival", common.ArrivalClosed, "Write: closed|fixed|poisson, closed sends the next request when the previous one returns (paced by -rate if set), "+
+		"fixed and poisson are open loop: each thread schedules requests at rate/T records per second with fixed or exponential intervals regardless of completion, "+
+		"latency is measured from the scheduled send time and the queue delay is reported separately. default closed.")
+	fs.StringVar(&o.onError, "onError", common.OnErrorFail, "Write: fail|continue|retry, what to do when a write request fails. fail stops all threads and fails the round, "+
+		"continue skips the request and counts its records as failed, retry retries transient errors (connection loss, timeouts, conflicts, overload) "+
+		"with exponential backoff up to writeRetries times and then skips the request, other errors fail the round. "+
+		"Throughput only counts acknowledged records. default fail.")
+	fs.IntVar(&o.retries, "writeRetries", common.DefaultRetryPolicy.MaxRetries, "Write: maximum retries of a failed request when onError is retry. default 3.")
+	fs.DurationVar(&o.retryDelay, "retryDelay", common.DefaultRetryPolicy.BaseDelay, "Write: wait before the first retry, doubled on each further retry. default 100ms.")
+	fs.DurationVar(&o.retryMax, "retryMaxDelay", common.DefaultRetryPolicy.MaxDelay, "Write: upper bound of the wait between retries. default 5s.")
+	fs.Float64Var(&o.retryJitter, "retryJitter", common.DefaultRetryPolicy.Jitter, "Write: 0~1, each wait is shortened by a random fraction up to this value "+
+		"so that clients do not retry in lockstep. default 0.5.")
+	fs.StringVar(&o.verify, "verify", common.VerifyNone, "Write: none|count|checksum, after each round count the records of every written table and compare them "+
+		"with the acknowledged records, checksum also compares the sums of ts and the numeric columns. Lost or duplicated records fail the round. default none.")
+	fs.BoolVar(&o.check, "check", false, "Write: compute the expected count, sum, avg, max, min and latest value of the queried table from the acknowledged records "+
+		"and save them in the result. In a scenario the following query phases of the same target are checked against them. default false.")
+	fs.StringVar(&o.expect, "expect", "", "Query: check the query results against the expected values in this write result file (written with -check -json), "+
+		"within a tolerance for float precision. default none.")
+	fs.DurationVar(&o.duration, "duration", 0, "Write: run each test round for this long instead of n records per table, e.g. 10m or 48h for a soak test. 0 means use n. default 0.")
+	fs.DurationVar(&o.interval, "interval", 0, "Write: print the throughput and latency of every interval and save them to the result as a time series. "+
+		"0 means no interval report, or 10s with -duration. default 0.")
+	fs.DurationVar(&o.slo, "slo", common.DefaultSLO, "Saturate: upper bound of the p99 batch latency for a rate to be sustainable. default 100ms.")
+	fs.StringVar(&o.search, "search", common.SearchStep, "Saturate: step|binary, step raises the rate by rateStep until it is not sustainable, "+
+		"binary bisects between the highest sustainable and the lowest unsustainable rate. default step.")
+	fs.Float64Var(&o.rateStart, "rateStart", 10000, "Saturate: first rate to test, records/second. default 10000.")
+	fs.Float64Var(&o.rateStep, "rateStep", 0, "Saturate: rate increment of the step search, 0 means rateStart. default 0.")
+	fs.Float64Var(&o.rateMax, "rateMax", 0, "Saturate: highest rate to test, 0 means unlimited, the step search then stops after 100 rates and the binary search doubles the rate to find an upper bound. default 0.")
+	fs.Float64Var(&o.precision, "precision", common.DefaultPrecision, "Saturate: the binary search stops when the interval is narrower than this fraction of its upper bound. default 0.05.")
+	fs.IntVar(&o.readers, "readers", 1, "Mixed: number of query clients running alongside the T writers. default 1.")
+	fs.StringVar(&o.mix, "mix", "", "Mixed: queries and their ratios, e.g. 'point=4,avg=1,timeWindow=1,latest=4'. "+
+		"Queries: count|select|point|avg|sum|max|min|timeWindow|latest. Default is point, avg, timeWindow and latest in equal ratios, skipping unsupported ones.")
+	if err := fs.Parse(args); err != nil {
+		return o, err
+	}
+
+	o.target = strings.ToLower(o.target)
+	if alias, ok := targetAliases[o.target]; ok {
+		o.target = alias
+	}
+	info, ok := targets[o.target]
+	if !ok {
+		err := errors.New(fmt.Sprintf("unrecognized target value:%s, required to be mo|ck|td|influx|sr", o.target))
+		fmt.Printf("%v\n", err)
+		return o, err
+	}
+
+	// 未指定的参数使用原来对应工具的默认值
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for name, value := range info.defaults {
+		if !set[name] {
+			fs.Set(name, value)
+		}
+	}
+	if cmd == "query" && !set["T"] {
+		o.T = "1"
+	}
+	if o.conf == "" {
+		o.conf = info.confDir + "/conf/db.conf"
+	}
+
+	o.schema = common.DefaultSchema()
+	if o.schemaFile != "" {
+		schema, err := common.LoadSchema(o.schemaFile)
+		if err != nil {
+			return o, err
+		}
+		o.schema = schema
+	}
+	if set["devices"] {
+		if o.devices < 0 {
+			err := errors.New(fmt.Sprintf("invalid devices value:%d", o.devices))
+			fmt.Printf("%v\n", err)
+			return o, err
+		}
+		o.schema.Devices = o.devices
+	}
+	return o, nil
+}
+
+func (o *options) intArgs() (err error, r1, T1, n1, retry1 int) {
+	return common.GetIntArgs(o.r, o.T, o.n, o.retry)
+}
+
+func (o *options) writeParams() (error, common.WriteParams) {
+	err, r1, T1, n1, retry1 := o.intArgs()
+	if err != nil {
+		return err, common.WriteParams{}
+	}
+	gen, err := common.ParseGeneratorSpec(o.gen, o.seed, o.schema)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return err, common.WriteParams{}
+	}
+	if o.rate < 0 {
+		err = errors.New(fmt.Sprintf("invalid rate value:%f", o.rate))
+		fmt.Printf("%v\n", err)
+		return err, common.WriteParams{}
+	}
+	return nil, common.WriteParams{
+		R:           r1,
+		T:           T1,
+		N:           n1,
+		Retry:       retry1,
+		Interactive: o.interactive,
+		Pause:       o.pause,
+		PreRound:    o.preRound,
+		QueueDepth:  o.queue,
+		Rate:        o.rate,
+		Arrival:     o.arrival,
+		OnError:     o.onError,
+		Duration:    o.duration,
+		Interval:    o.interval,
+		Verify:      o.verify,
+		Check:       o.check,
+		Gen:         gen,
+		Backoff:     common.RetryPolicy{MaxRetries: o.retries, BaseDelay: o.retryDelay, MaxDelay: o.retryMax, Jitter: o.retryJitter},
+	}
+}
+
+func (o *options) mixedParams() (error, common.MixedParams) {
+	m := common.MixedParams{Readers: o.readers, QPS: o.qps}
+	if o.mix != "" {
+		err, mix := common.ParseMix(o.mix)
+		if err != nil {
+			return err, m
+		}
+		m.Mix = mix
+	}
+	return nil, m
+}
+
+func (o *options) saturationParams() common.SaturationParams {
+	return common.SaturationParams{
+		SLO:       o.slo,
+		Search:    o.search,
+		Start:     o.rateStart,
+		Step:      o.rateStep,
+		Max:       o.rateMax,
+		Precision: o.precision,
+	}
+}
+
+func (o *options) queryParams() (error, common.QueryParams) {
+	err, _, T1, _, _ := o.intArgs()
+	if err != nil {
+		return err, common.QueryParams{}
+	}
+	if o.iterations <= 0 || o.warmup < 0 || o.qps < 0 {
+		err = errors.New(fmt.Sprintf("invalid query args: iterations=%d, warmup=%d, qps=%f", o.iterations, o.warmup, o.qps))
+		fmt.Printf("%v\n", err)
+		return err, common.QueryParams{}
+	}
+	return nil, common.QueryParams{
+		T:          T1,
+		Iterations: o.iterations,
+		Warmup:     o.warmup,
+		Concurrent: o.concurrent,
+		QPS:        o.qps,
+	}
+}
+
+// params 写入测试结果的参数
+func (o *options) params(cmd string) map[string]string {
+	params := map[string]string{
+		"T":      o.T,
+		"mode":   o.mode,
+		"schema": o.schema.String(),
+	}
+	if o.schemaFile != "" {
+		params["schemaFile"] = o.schemaFile
+	}
+	if cmd == "query" {
+		params["iterations"] = strconv.Itoa(o.iterations)
+		params["warmup"] = strconv.Itoa(o.warmup)
+		params["concurrent"] = strconv.FormatBool(o.concurrent)
+		params["qps"] = strconv.FormatFloat(o.qps, 'f', -1, 64)
+		if o.expect != "" {
+			params["expect"] = o.expect
+		}
+		return params
+	}
+	params["r"] = o.r
+	params["n"] = o.n
+	params["retry"] = o.retry
+	params["txc"] = o.txc
+	params["tType"] = o.tType
+	params["wType"] = o.wType
+	params["t"] = o.t
+	params["queue"] = strconv.Itoa(o.queue)
+	params["seed"] = strconv.FormatInt(o.seed, 10)
+	params["gen"] = o.gen
+	params["onError"] = o.onError
+	if o.verify != "" && o.verify != common.VerifyNone {
+		params["verify"] = o.verify
+	}
+	if o.check {
+		params["check"] = "true"
+	}
+	if o.onError == common.OnErrorRetry {
+		params["writeRetries"] = strconv.Itoa(o.retries)
+		params["retryDelay"] = o.retryDelay.String()
+		params["retryMaxDelay"] = o.retryMax.String()
+		params["retryJitter"] = strconv.FormatFloat(o.retryJitter, 'f', -1, 64)
+	}
+	ftoa := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if o.rate > 0 {
+		params["rate"] = ftoa(o.rate)
+		params["arrival"] = o.arrival
+	}
+	if o.duration > 0 {
+		params["duration"] = o.duration.String()
+	}
+	if o.interval > 0 {
+		params["interval"] = o.interval.String()
+	}
+	if cmd == "mixed" {
+		params["readers"] = strconv.Itoa(o.readers)
+		params["mix"] = o.mix
+		params["qps"] = ftoa(o.qps)
+	}
+	if cmd == "saturate" {
+		params["slo"] = o.slo.String()
+		params["search"] = o.search
+		params["arrival"] = o.arrival
+		params["rateStart"] = ftoa(o.rateStart)
+		params["rateStep"] = ftoa(o.rateStep)
+		params["rateMax"] = ftoa(o.rateMax)
+		params["precision"] = ftoa(o.precision)
+	}
+	return params
+}
+
+// writeResult 按 -json、-csv 参数输出测试结果, 按 -history 参数保存到历史记录
+func (o *options) writeResult(cmd string, result *common.Result) error {
+	if result == nil {
+		return nil
+	}
+	result.Params = o.params(cmd)
+	result.Label = o.label
+	result.Phase = o.phase
+	if o.history != "" {
+		if err := common.AppendHistory(o.history, result); err != nil {
+			return err
+		}
+	}
+	if o.jsonFile != "" {
+		if err := result.WriteJSON(o.jsonFile); err != nil {
+			return err
+		}
+	}
+	if o.csvFile != "" {
+		if err := result.WriteCSV(o.csvFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newBackend(o *options) (error, common.Backend) {
+	info := targets[o.target]
+	if info.name == common.SR {
+		srConfig, err := common.ReadSRFile(o.conf)
+		if err != nil {
+			return err, nil
+		}
+		return nil, common.NewSRBackend(srConfig, o.schema)
+	}
+
+	dbConfig, err := common.ReadDBFile(o.conf, info.name)
+	if err != nil {
+		return err, nil
+	}
+	fmt.Printf("dbConfig:%v\n", *dbConfig)
+
+	switch info.name {
+	case common.MO:
+		txc1, err := strconv.Atoi(o.txc)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return err, nil
+		}
+		if txc1 > 0 {
+			fmt.Printf("开始事务提交写入, 一次事务提交的写入: %d\n", txc1)
+		}
+		return nil, common.NewMOBackend(dbConfig, o.mode, o.tType, o.wType, txc1, o.schema)
+	case common.CK:
+		return nil, common.NewCKBackend(dbConfig, o.mode, o.schema)
+	case common.TDengine:
+		if !taosSupported {
+			err = errors.New("TDengine driver is not compiled in, rebuild with '-tags taos'")
+			fmt.Printf("%v\n", err)
+			return err, nil
+		}
+		t1, err := strconv.Atoi(o.t)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return err, nil
+		}
+		return nil, common.NewTDengineBackend(dbConfig, o.mode, t1, o.wType, o.schema)
+	default:
+		return nil, common.NewInfluxBackend(dbConfig, o.mode, o.schema)
+	}
+}
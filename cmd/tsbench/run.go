@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"performance_testing/common"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scenario 测试场景, 对 targets 中的每个数据库依次执行全部阶段, 最后输出合并的报告, 如:
+//
+//	name: nightly
+//	targets: [mo, ck, td]
+//	schema: schema.yaml
+//	params: {T: 7, r: 10000, n: 500000, devices: 1000}
+//	targetParams:
+//	  mo: {wType: loadLine}
+//	phases:
+//	  - name: load
+//	    write: {retry: 3}
+//	  - pause: 30s
+//	  - name: threads
+//	    write: {}
+//	    sweep: {T: [1, 4, 8], r: ["1000..100000:x10"]}
+//	    sweepMode: oat
+//	  - query: {T: 1, iterations: 10, warmup: 2}
+//	    targets: [mo]
+//	  - name: capacity
+//	    saturate: {slo: 50ms, search: binary, rateStart: 100000}
+//	  - mixed: {duration: 10m, readers: 4, mix: "point=4,latest=4,avg=1"}
+//	report: {json: report.json, csv: report.csv}
+//
+// 参数按 params、targetParams、阶段参数、sweep 的顺序覆盖, 名称同子命令参数。
+// 写入阶段指定 check 时, 之后同一数据库的查询阶段用写入计算的聚合值校验查询结果, 报告中输出正确性矩阵。
+// 也可以用 steps 按顺序执行子命令, 如 - write --target mo -T 7 -r 10000 -wType loadLine
+type scenario struct {
+	Name         string                       `yaml:"name"`
+	Targets      []string                     `yaml:"targets"`
+	Schema       string                       `yaml:"schema"`
+	Params       map[string]string            `yaml:"params"`
+	TargetParams map[string]map[string]string `yaml:"targetParams"`
+	Phases       []phase                      `yaml:"phases"`
+	Report       struct {
+		Json string `yaml:"json"`
+		Csv  string `yaml:"csv"`
+	} `yaml:"report"`
+
+	Steps []string `yaml:"steps"`
+}
+
+// phase 场景中的一个阶段, write、query、saturate、mixed、pause 五者之一
+type phase struct {
+	Name     string              `yaml:"name"`
+	Write    map[string]string   `yaml:"write"`
+	Query    map[string]string   `yaml:"query"`
+	Saturate map[string]string   `yaml:"saturate"`
+	Mixed    map[string]string   `yaml:"mixed"`
+	Pause    time.Duration       `yaml:"pause"`
+	Targets  []string            `yaml:"targets"` // 只对这些数据库执行, 默认全部
+	Sweep    map[string][]string `yaml:"sweep"`   // 扫描的参数, 取值可以是范围, 同 -sweep
+	// cartesian|oat, 默认 cartesian 每种取值组合各执行一次, oat 每次只改变一个参数
+	SweepMode string `yaml:"sweepMode"`
+}
+
+// sweepParams 按参数名排序并展开取值范围
+func (p *phase) sweepParams() (error, []common.SweepParam) {
+	var names []string
+	for k := range p.Sweep {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var params []common.SweepParam
+	for _, k := range names {
+		err, values := common.ExpandSweepValues(p.Sweep[k])
+		if err != nil {
+			return err, nil
+		}
+		params = append(params, common.SweepParam{Name: k, Values: values})
+	}
+	return nil, params
+}
+
+func (p *phase) command() string {
+	switch {
+	case p.Write != nil:
+		return "write"
+	case p.Query != nil:
+		return "query"
+	case p.Saturate != nil:
+		return "saturate"
+	case p.Mixed != nil:
+		return "mixed"
+	}
+	return "pause"
+}
+
+func (p *phase) runsOn(target string) bool {
+	if len(p.Targets) == 0 {
+		return true
+	}
+	for _, t := range p.Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+func runScenario(args []string) error {
+	if len(args) != 1 {
+		err := errors.New("usage: tsbench run scenario.yaml")
+		fmt.Printf("%v\n", err)
+		return err
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Printf("read scenario file fail, err:%v\n", err)
+		return err
+	}
+	var s scenario
+	if err = yaml.Unmarshal(data, &s); err != nil {
+		fmt.Printf("parse scenario file %s fail, err:%v\n", args[0], err)
+		return err
+	}
+	if err = s.check(); err != nil {
+		return err
+	}
+
+	if len(s.Steps) > 0 {
+		return s.runSteps()
+	}
+	if s.Name == "" {
+		s.Name = args[0]
+	}
+	return s.run()
+}
+
+// check 校验阶段定义
+func (s *scenario) check() error {
+	if len(s.Steps) > 0 && len(s.Phases) > 0 {
+		err := errors.New("scenario can not have both steps and phases")
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	if len(s.Phases) > 0 && len(s.Targets) == 0 {
+		err := errors.New("scenario has no targets")
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	for i, p := range s.Phases {
+		var kinds int
+		if p.Write != nil {
+			kinds++
+		}
+		if p.Query != nil {
+			kinds++
+		}
+		if p.Saturate != nil {
+			kinds++
+		}
+		if p.Mixed != nil {
+			kinds++
+		}
+		if p.Pause > 0 {
+			kinds++
+		}
+		if kinds != 1 {
+			err := errors.New(fmt.Sprintf("scenario phase[%d] must have exactly one of write, query, saturate, mixed or pause, use 'write: {}' for default parameters", i+1))
+			fmt.Printf("%v\n", err)
+			return err
+		}
+		if err, _ := p.sweepParams(); err != nil {
+			fmt.Printf("scenario phase[%d] has invalid sweep\n", i+1)
+			return err
+		}
+		if p.SweepMode != "" && p.SweepMode != common.SweepCartesian && p.SweepMode != common.SweepOneAtATime {
+			err := errors.New(fmt.Sprintf("scenario phase[%d] has unrecognized sweepMode:%s, required to be cartesian|oat", i+1, p.SweepMode))
+			fmt.Printf("%v\n", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *scenario) runSteps() error {
+	for i, step := range s.Steps {
+		fields := strings.Fields(step)
+		if len(fields) == 0 || fields[0] == "run" {
+			err := errors.New(fmt.Sprintf("invalid scenario step[%d]:%s", i+1, step))
+			fmt.Printf("%v\n", err)
+			return err
+		}
+		fmt.Printf("======== scenario step %d/%d: %s ========\n", i+1, len(s.Steps), step)
+		if err := runCommand(fields[0], fields[1:]); err != nil {
+			fmt.Printf("scenario step %d fail, err:%v\n", i+1, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// run 对每个数据库依次执行全部阶段。一个阶段失败时跳过该数据库余下的阶段, 继续测试下一个数据库, 最后返回第一个错误
+func (s *scenario) run() error {
+	report := &common.Report{Name: s.Name, StartTime: time.Now()}
+	var firstErr error
+	for _, target := range s.Targets {
+		for i, p := range s.Phases {
+			if !p.runsOn(target) {
+				continue
+			}
+			name := p.Name
+			if name == "" {
+				name = fmt.Sprintf("phase%d", i+1)
+			}
+			if p.command() == "pause" {
+				fmt.Printf("======== scenario %s, %s: pause %v ========\n", target, name, p.Pause)
+				time.Sleep(p.Pause)
+				continue
+			}
+
+			err := s.runPhase(report, target, name, p)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s %s: %v", target, name, err))
+				if firstErr == nil {
+					firstErr = err
+				}
+				break
+			}
+		}
+	}
+	report.EndTime = time.Now()
+
+	report.Print()
+	if s.Report.Json != "" {
+		if err := report.WriteJSON(s.Report.Json); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if s.Report.Csv != "" {
+		if err := report.WriteCSV(s.Report.Csv); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// runPhase 对数据库 target 执行一个写入、查询、饱和点搜索或混合读写阶段, 有 sweep 时每种参数组合执行一次
+func (s *scenario) runPhase(report *common.Report, target, name string, p phase) error {
+	params := p.Write
+	switch p.command() {
+	case "query":
+		params = p.Query
+	case "saturate":
+		params = p.Saturate
+	case "mixed":
+		params = p.Mixed
+	}
+	_, sweep := p.sweepParams()
+	points := common.SweepPoints(sweep, p.SweepMode)
+	for j, point := range points {
+		args := []string{"--target", target, "-phase=" + name}
+		if s.Schema != "" {
+			args = append(args, "-schema="+s.Schema)
+		}
+		args = append(args, flagArgs(s.Params, s.TargetParams[target], params, point)...)
+
+		label := name
+		if len(points) > 1 {
+			label = fmt.Sprintf("%s %d/%d %v", name, j+1, len(points), point)
+		}
+		fmt.Printf("======== scenario %s, %s: %s %s ========\n", target, label, p.command(), strings.Join(args, " "))
+
+		var err error
+		var result *common.Result
+		switch p.command() {
+		case "write":
+			err, result = runWrite(args)
+		case "query":
+			err, result = runQuery(args)
+		case "saturate":
+			err, result = runSaturate(args)
+		default:
+			err, result = runMixed(args)
+		}
+		if err == nil && result != nil && result.Command == "query" && result.Params["expect"] == "" {
+			err = checkQueries(report, result)
+		}
+		if result != nil {
+			report.Results = append(report.Results, result)
+		}
+		if err != nil {
+			fmt.Printf("scenario %s, %s fail, err:%v\n", target, label, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// checkQueries 同一数据库之前的写入阶段指定了 -check 时, 用最近一次写入计算的聚合值校验查询结果
+func checkQueries(report *common.Report, result *common.Result) error {
+	for i := len(report.Results) - 1; i >= 0; i-- {
+		r := report.Results[i]
+		if r.Target != result.Target || r.Command == "query" {
+			continue
+		}
+		if r.Expected == nil {
+			return nil
+		}
+		return common.CheckQueries(result, r.Expected)
+	}
+	return nil
+}
+
+// flagArgs 将依次覆盖的参数转换为命令行参数 -name=value, 按参数名排序
+func flagArgs(layers ...map[string]string) []string {
+	merged := map[string]string{}
+	for _, layer := range layers {
+		for k, v := range layer {
+			merged[k] = v
+		}
+	}
+	var names []string
+	for k := range merged {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var args []string
+	for _, k := range names {
+		args = append(args, "-"+k+"="+merged[k])
+	}
+	return args
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"performance_testing/common"
+	"strings"
+	"time"
+)
+
+// runSweep 对扫描参数的每个组合执行一次写入测试, 其他参数取命令行的值。
+// 每次写入都由 InitTable 重建数据库和表, 多轮测试之间由 TruncateTables 清空。
+// -json、-csv 输出全部组合的合并报告, 其中包含写入速度与耗时随参数变化的曲线
+func runSweep(args []string) error {
+	o, err := parseOptions("write", args)
+	if err != nil {
+		return err
+	}
+	if o.sweepMode != common.SweepCartesian && o.sweepMode != common.SweepOneAtATime {
+		err = errors.New(fmt.Sprintf("unrecognized sweepMode value:%s, required to be cartesian|oat", o.sweepMode))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	err, params := common.ParseSweep(o.sweep)
+	if err != nil {
+		return err
+	}
+
+	points := common.SweepPoints(params, o.sweepMode)
+	report := &common.Report{Name: "sweep " + o.sweep, StartTime: time.Now()}
+	results := make([]*common.Result, len(points))
+	var failed int
+	for i, point := range points {
+		var names []string
+		for _, p := range params {
+			if v, ok := point[p.Name]; ok {
+				names = append(names, p.Name+"="+v)
+			}
+		}
+		label := strings.Join(names, ",")
+		// 每个组合不单独输出结果文件
+		pointArgs := append(append([]string{}, args...), flagArgs(point, map[string]string{"phase": label, "json": "", "csv": ""})...)
+		fmt.Printf("======== sweep point %d/%d: %s ========\n", i+1, len(points), label)
+
+		err, result := runWrite(pointArgs)
+		results[i] = result
+		if result != nil {
+			report.Results = append(report.Results, result)
+		}
+		if err != nil {
+			failed++
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", label, err))
+		}
+	}
+	report.EndTime = time.Now()
+	report.Series = common.SweepSeries(params, points, results)
+	common.PrintSweep(params, points, results, report.Series)
+
+	if o.jsonFile != "" {
+		if err = report.WriteJSON(o.jsonFile); err != nil {
+			return err
+		}
+	}
+	if o.csvFile != "" {
+		if err = report.WriteCSV(o.csvFile); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errors.New(fmt.Sprintf("%d of %d sweep points failed", failed, len(points)))
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build taos
+
+package main
+
+// TDengine 原生驱动依赖 cgo 和 TDengine 客户端(taos.h、libtaos), 需使用 go build -tags taos ./cmd/tsbench 编译
+import _ "github.com/taosdata/driver-go/v3/taosSql"
+
+const taosSupported = true
@@ -0,0 +1,667 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var confirm string
+
+// Backend 是被测数据库需要实现的接口, 重试、计时和写入速度统计统一由 RunWrite、RunQuery 完成
+type Backend interface {
+	// Name 返回数据库名称, 如 MO、CK
+	Name() string
+	// CheckArgs 校验写入参数
+	CheckArgs(p WriteParams) error
+	// Connect 创建 T1 个客户端连接, 每个协程独占一个
+	Connect(T1 int) error
+	// InitTable 删除并重建 test 数据库, 再创建要写入的表
+	InitTable(T1 int) error
+	// TruncateTables 清空上一轮测试写入的数据
+	TruncateTables(T1 int) error
+	// EncodeBatch 将一批记录转换为第 worker 个客户端的写入请求(sql、BatchPoints 等)
+	EncodeBatch(worker int, records []Record) (error, interface{})
+	// WriteBatch 由第 worker 个客户端执行一次写入请求
+	WriteBatch(worker int, batch interface{}) error
+	// Flush 在第 worker 个客户端写完后调用, 如提交未结束的事务
+	Flush(worker int) error
+	// Queries 返回支持的查询测试项, 按执行顺序排列
+	Queries() []string
+	// RunQuery 由第 worker 个客户端执行一次查询, 返回 count 值、聚合值或读取的行数
+	RunQuery(worker int, name string) (error, float64)
+	Close()
+}
+
+// MultiTableBackend 由数据库实现, 表示 T 个客户端分摊 Tables() 张表写入, 每张表写入 N 条记录,
+// 如 TDengine 的多表写入。Tables() 为 0 时仍为每个客户端写一张表
+type MultiTableBackend interface {
+	Tables() int
+}
+
+// LoadedRowsBackend 由写入结果中带有导入行数的数据库实现, 如 StarRocks Stream Load,
+// 每轮写入的记录数取数据库返回的导入行数, 而不是按参数计算的记录数
+type LoadedRowsBackend interface {
+	// LoadedRows 返回上次调用以来导入成功的行数
+	LoadedRows() int64
+}
+
+// TxBackend 由以事务批量提交写入请求的数据库实现, 如 MO 指定 txc 时。WriteBatch 成功的请求在事务提交后才算写入成功,
+// 事务中任一请求或提交失败时整个事务回滚
+type TxBackend interface {
+	// TxBatches 每个事务的写入请求数, WriteBatch 执行第 TxBatches 个请求后提交事务, Flush 提交未满的事务; 0 表示不使用事务
+	TxBatches() int
+}
+
+// QueryArgsBackend 由只在部分写入方式下支持查询测试的数据库实现, 在查询测试及混合读写测试开始前校验
+type QueryArgsBackend interface {
+	CheckQueryArgs() error
+}
+
+// checkQueryArgs 数据库实现 QueryArgsBackend 时校验能否执行查询测试
+func checkQueryArgs(b Backend) error {
+	if qb, ok := b.(QueryArgsBackend); ok {
+		return qb.CheckQueryArgs()
+	}
+	return nil
+}
+
+// SkipNullBackend 由不能写入全部列为 NULL 的记录的数据库实现, 如行协议的一行至少要有一个字段。
+// SkipAllNull 为 true 时这样的记录在编码前跳过, 不计入写入的记录数与期望结果, 单独计入跳过的记录数
+type SkipNullBackend interface {
+	SkipAllNull() bool
+}
+
+// VersionBackend 由能查询服务端版本的数据库实现, 版本写入测试结果
+type VersionBackend interface {
+	// Version 在 Connect 之后调用, 查询失败时返回空
+	Version() string
+}
+
+// WriteParams 写入测试的公共参数
+type WriteParams struct {
+	R     int // 每次请求写入的记录数
+	T     int // 客户端(协程)数
+	N     int // 每张表写入的记录数
+	Retry int // 测试轮数
+
+	Interactive bool          // 每轮开始前是否等待确认, 仅在 stdin 为终端时生效
+	Pause       time.Duration // 两轮测试之间的等待时间
+	PreRound    string        // 每轮开始前执行的命令, 如清理缓存、触发 compaction
+	QueueDepth  int           // 每个客户端生成端与写入端之间的队列容量(请求数)
+	Rate        float64       // 所有客户端合计的目标写入速度(records/second), 按此速度安排每个请求的发送时间, 0 表示不限制
+	Arrival     string        // 请求的到达方式 closed|fixed|poisson, 默认 closed
+	OnError     string        // 写入请求失败时的处理方式 fail|continue|retry, 默认 fail
+	Backoff     RetryPolicy   // OnError 为 retry 时的重试次数与退避等待时间
+	Duration    time.Duration // 每轮写入的时长, 指定时不断生成数据直到时间结束, 忽略 N
+	Interval    time.Duration // 每隔 Interval 输出一次区间的写入速度与耗时, 0 表示不输出; 指定 Duration 时默认 10s
+	Verify      string        // 每轮写入后的校验方式 none|count|checksum, 默认 none
+	Check       bool          // 写入时计算查询测试读取的记录的聚合值, 用于校验查询结果
+	Gen         *GeneratorSpec
+
+	tables int // 客户端分摊写入的表数, 由 MultiTableBackend 提供
+}
+
+// workload 返回第 worker 个客户端写入的时间线(设备或表)范围 [first, first+series) 与记录数, series 为 0 表示只写一张表
+func (p WriteParams) workload(worker int) (first, series, rows int) {
+	switch {
+	case p.tables > 0:
+		first, series = splitRange(p.tables, worker, p.T)
+		return first, series, series * p.N
+	case p.Gen.Schema.Devices > 0:
+		first, series = p.Gen.Schema.DeviceRange(worker, p.T)
+		return first, series, p.N
+	}
+	return 0, 0, p.N
+}
+
+// Record 一条测试数据: ts 及 Schema.Columns 各列的值, 值为 int64、float64、bool、string, NULL 为 nil
+type Record struct {
+	Ts     int64 // 毫秒时间戳
+	Device int   // 设备序号, 仅在按设备写入时有效
+	Values []interface{}
+	Tags   []interface{} // 设备的标签值, 仅在按设备写入时有效
+}
+
+// AllNull 全部列是否都为 NULL
+func (r Record) AllNull() bool {
+	for _, v := range r.Values {
+		if v != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Record) TsString() string {
+	return time.UnixMilli(r.Ts).Format(TsLayout)
+}
+
+// TableName 根据写入模式返回第 worker 个客户端写入的表名(不含库名)
+// multi为多表写入，第一个客户端向d0表写，第二个客户端向d1表写……以此类推
+// single为单表写入模式。不管几个客户端，都向d0表写数据
+func TableName(mode, tablePrefix string, worker int) string {
+	if mode == Multi {
+		return tablePrefix + strconv.Itoa(worker)
+	}
+	return Table
+}
+
+func CheckMode(mode string) error {
+	if mode != Multi && mode != Single {
+		err := fmt.Errorf("unrecognized mode value:%s, required to be either multi or single, default multi", mode)
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	return nil
+}
+
+// RunWrite 执行写入测试: 建表, 然后每轮开启 T 个客户端边生成数据边并行写入, 统计写入速度。
+// 出错时也返回已完成轮次的结果
+func RunWrite(b Backend, p WriteParams) (error, *Result) {
+	result := newResult("write", b)
+	if err := prepareWrite(b, &p, p.T); err != nil {
+		return result.finish(err)
+	}
+	defer b.Close()
+	result.setVersion(b)
+
+	interactive := p.Interactive
+	if interactive && !IsTerminal(os.Stdin) {
+		fmt.Printf("stdin is not a terminal, run in batch mode.\n")
+		interactive = false
+	}
+
+	var sumRecord float64
+	// 全部轮次每次写入请求的耗时与开环写入的排队时间
+	var all PipelineStats
+	// 每个测试测 retry 轮，求平均值
+	for k := 0; k < p.Retry; k++ {
+		if interactive {
+			fmt.Printf("按 Y 或者 回车键,将开始插入数据,按 N 将退出, 开的第%d次测试\n", k+1)
+			fmt.Scanln(&confirm)
+			confirm = strings.TrimSpace(strings.ToUpper(confirm))
+			if confirm != "Y" && confirm != "" {
+				fmt.Printf("exist.\n")
+				return result.finish(nil)
+			}
+		}
+
+		if err := beforeRound(b, p, k); err != nil {
+			return result.finish(err)
+		}
+		err, round, stats := writeRound(b, p, k)
+		result.Rounds = append(result.Rounds, round)
+		if p.Check {
+			// 每轮写入前清空表, 表中只有最后一轮写入的数据
+			result.Expected = stats.aggregates
+		}
+		if err != nil {
+			return result.finish(err)
+		}
+		all.add(stats)
+		sumRecord += round.Throughput
+	}
+	recordsLast := sumRecord / float64(p.Retry)
+	fmt.Printf("======== avg test: %f/%d = %f records/second ===========\n", sumRecord, p.Retry, recordsLast)
+	if all.Latency != nil {
+		fmt.Printf("======== batch latency of %d tests: %s ===========\n", p.Retry, all.Latency)
+		result.Latency = NewLatencyResult(all.Latency.Summary())
+	}
+	if all.QueueDelay != nil {
+		fmt.Printf("======== queue delay of %d tests: %s ===========\n", p.Retry, all.QueueDelay)
+		result.QueueDelay = NewLatencyResult(all.QueueDelay.Summary())
+	}
+	if all.RetryLatency != nil {
+		fmt.Printf("======== retries of %d tests: %d retries in %d requests, retry latency: %s ===========\n", p.Retry, all.Retries, all.Retried, all.RetryLatency)
+		result.RetryLatency = NewLatencyResult(all.RetryLatency.Summary())
+	}
+	result.Throughput = recordsLast
+	return result.finish(nil)
+}
+
+// prepareWrite 校验参数, 获取 conns 个数据库连接(前 T 个用于写入)并建表, 成功后由调用方关闭连接
+func prepareWrite(b Backend, p *WriteParams, conns int) error {
+	if err := b.CheckArgs(*p); err != nil {
+		return err
+	}
+	if err := CheckArrival(p.Arrival, p.Rate); err != nil {
+		return err
+	}
+	if err := CheckOnError(p.OnError, p.Backoff); err != nil {
+		return err
+	}
+	if err := CheckVerify(b, p.Verify); err != nil {
+		return err
+	}
+	if _, ok := b.(QueriedBackend); p.Check && !ok {
+		err := errors.New(fmt.Sprintf("%s does not support check", b.Name()))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	if p.Duration < 0 || p.Interval < 0 {
+		err := errors.New(fmt.Sprintf("invalid duration(%v) or interval(%v)", p.Duration, p.Interval))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	if p.Duration > 0 && p.Interval == 0 {
+		p.Interval = DefaultInterval
+	}
+	if p.Gen == nil {
+		p.Gen = DefaultGeneratorSpec()
+	}
+	fmt.Printf("data generator: %s\n", p.Gen)
+	if devices := p.Gen.Schema.Devices; devices > 0 && devices < p.T {
+		err := errors.New(fmt.Sprintf("devices(%d) must be at least the number of threads(%d)", devices, p.T))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	if mb, ok := b.(MultiTableBackend); ok {
+		p.tables = mb.Tables()
+		if p.tables > 0 && p.tables < p.T {
+			err := errors.New(fmt.Sprintf("tables(%d) must be at least the number of threads(%d)", p.tables, p.T))
+			fmt.Printf("%v\n", err)
+			return err
+		}
+	}
+
+	// 获取 conns 个数据库连接
+	if err := b.Connect(conns); err != nil {
+		fmt.Printf("get dbconn fail:%v\n", err)
+		return err
+	}
+	fmt.Printf("%s all clinet(%d thread) has ready!\n", b.Name(), conns)
+
+	// 初始化数据库表，导入数据前先删除、新建test数据库，再创建表d0、d1、d2……
+	if err := b.InitTable(p.T); err != nil {
+		b.Close()
+		return err
+	}
+	return nil
+}
+
+// beforeRound 第 k 轮(从 0 开始)写入前的准备: 除第一轮外先清空表并等待 Pause, 然后执行 PreRound 命令
+func beforeRound(b Backend, p WriteParams, k int) error {
+	if k != 0 {
+		if err := b.TruncateTables(p.T); err != nil {
+			return err
+		}
+		fmt.Printf("tables has truncated, pause %v before next test.\n", p.Pause)
+		time.Sleep(p.Pause)
+	}
+
+	if p.PreRound != "" {
+		if err := RunHook(p.PreRound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRound 执行第 k 轮写入: 开启 T 个客户端, 每个客户端由生成协程供数据, 写入协程并行执行写入操作。
+// 写入速度只按写入成功的记录数计算, 出错时也返回本轮已完成部分的结果。
+// 指定 Verify 时写入完成后按表校验记录数与校验和, 数据丢失或重复时返回错误
+func writeRound(b Backend, p WriteParams, k int) (error, RoundResult, PipelineStats) {
+	if p.Duration > 0 {
+		fmt.Printf("test %d runs for %v, reports every %v\n", k+1, p.Duration, p.Interval)
+	}
+	if p.Rate > 0 {
+		arrival := p.Arrival
+		if arrival == "" {
+			arrival = ArrivalClosed
+		}
+		fmt.Printf("start test %d at %.0f records/second (%s arrival) …….\n", k+1, p.Rate, arrival)
+	} else {
+		fmt.Printf("start test %d …….\n", k+1)
+	}
+	lb, loaded := b.(LoadedRowsBackend)
+	if loaded {
+		lb.LoadedRows()
+	}
+
+	// 开始执行
+	startTime := time.Now()
+	stats, err := runPipeline(b, p)
+	spendT := time.Since(startTime).Seconds()
+	if err != nil {
+		fmt.Printf("%d test fail:%v\n", k+1, err)
+	}
+	fmt.Printf("spend time:%f s\n", spendT)
+	stats.Print()
+
+	count := stats.Rows
+	if loaded {
+		count = int(lb.LoadedRows())
+		if count != stats.Rows {
+			fmt.Printf("%s loaded %d of %d records\n", b.Name(), count, stats.Rows)
+		}
+	}
+	records := float64(count) / spendT
+	fmt.Printf("%d test: %d/%f = %f records/second\n", k+1, count, spendT, records)
+	if stats.Errors > 0 || stats.Retries > 0 {
+		fmt.Printf("%d test: %d records failed in %d requests, %d retries in %d requests\n", k+1, stats.FailedRows, stats.Errors, stats.Retries, stats.Retried)
+		for _, w := range stats.Workers {
+			fmt.Printf("  worker %d: %d records written, %d records failed in %d requests, %d retries in %d requests\n",
+				w.Worker, w.Rows, w.FailedRows, w.Errors, w.Retries, w.Retried)
+		}
+	}
+	if stats.Skipped > 0 {
+		fmt.Printf("%d test: %d records with all columns NULL skipped, %s cannot write them\n", k+1, stats.Skipped, b.Name())
+	}
+	round := RoundResult{
+		Round:      k + 1,
+		StartTime:  startTime,
+		EndTime:    time.Now(),
+		Records:    count,
+		Seconds:    spendT,
+		Throughput: records,
+		TargetRate: p.Rate,
+		FailedRows: stats.FailedRows,
+		Skipped:    stats.Skipped,
+		Errors:     stats.Errors,
+		Retries:    stats.Retries,
+		Bottleneck: stats.Bottleneck(),
+		Latency:    NewLatencyResult(stats.Latency.Summary()),
+	}
+	if stats.Errors > 0 || stats.Retries > 0 {
+		round.Workers = stats.Workers
+	}
+	if stats.RetryLatency != nil {
+		round.Retried = stats.Retried
+		round.RetryLatency = NewLatencyResult(stats.RetryLatency.Summary())
+	}
+	if stats.QueueDelay != nil {
+		round.QueueDelay = NewLatencyResult(stats.QueueDelay.Summary())
+	}
+	round.Intervals = stats.Intervals
+	if err == nil && p.Verify != "" && p.Verify != VerifyNone {
+		if err, round.Verify = verifyTables(b, p, stats.expected); err == nil {
+			err = PrintVerify(b.Name(), round.Verify)
+		}
+	}
+	return err, round, stats
+}
+
+// IsTerminal 判断 f 是否为终端(TTY)
+func IsTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// RunHook 通过 bash 执行每轮测试前的钩子命令, 命令失败时终止测试
+func RunHook(command string) error {
+	fmt.Printf("run pre-round hook: %s\n", command)
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("run pre-round hook fail, err:%v\n", err)
+		return err
+	}
+	return nil
+}
+
+var queryLabels = map[string]string{
+	QueryNameCount:      "'count(*)' query",
+	QueryNamePoint:      "'point query'",
+	QueryNameAvg:        "'avg' query",
+	QueryNameSum:        "'sum' query",
+	QueryNameMax:        "'max' query",
+	QueryNameMin:        "'min' query",
+	QueryNameTimeWindow: "TimeWindow query",
+	QueryNameLatest:     "'latest value' query",
+}
+
+// QueryParams 查询测试的公共参数
+type QueryParams struct {
+	T          int     // 客户端(协程)数
+	Iterations int     // 每个查询测量的次数
+	Warmup     int     // 每个查询测量前预热执行的次数, 不计入统计
+	Concurrent bool    // 全部查询都由 T 个客户端并发执行, 否则只有 select * 并发执行
+	QPS        float64 // 并发执行时所有客户端合计的目标 QPS, 0 表示不限制
+}
+
+// pacer 按目标 QPS 为多个客户端安排请求的发送时间
+type pacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newPacer(qps float64) *pacer {
+	if qps <= 0 {
+		return nil
+	}
+	return &pacer{interval: time.Duration(float64(time.Second) / qps), next: time.Now()}
+}
+
+// wait 等待到下一个发送时间, nil 表示不限制
+func (p *pacer) wait() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	send := p.next
+	p.next = p.next.Add(p.interval)
+	p.mu.Unlock()
+	time.Sleep(time.Until(send))
+}
+
+// RunQuery 依次执行 b 支持的查询测试项, 每项预热 Warmup 次后测量 Iterations 次, 输出耗时分布。
+// select * 由 T 个客户端并发执行, 每次测量的是 T 个查询全部完成的时间;
+// Concurrent 时每项查询都由 T 个客户端各执行 Iterations 次, 按 QPS 限速
+func RunQuery(b Backend, p QueryParams) (error, *Result) {
+	result := newResult("query", b)
+	if p.Iterations <= 0 {
+		p.Iterations = 1
+	}
+	if err := checkQueryArgs(b); err != nil {
+		return result.finish(err)
+	}
+	if err := b.Connect(p.T); err != nil {
+		fmt.Printf("get dbconn fail:%v\n", err)
+		return result.finish(err)
+	}
+	defer b.Close()
+	fmt.Printf("%s all clinet(%d thread) has ready!\n", b.Name(), p.T)
+	result.setVersion(b)
+
+	var count int
+	for _, name := range b.Queries() {
+		if p.Concurrent {
+			err, qr := runConcurrentQuery(b, p, name, count)
+			result.Queries = append(result.Queries, qr)
+			if err != nil && name == QueryNameCount {
+				return result.finish(err)
+			}
+			if name == QueryNameCount {
+				count = int(qr.Value)
+			}
+			continue
+		}
+
+		qr := QueryResult{Name: name, StartTime: time.Now(), Clients: 1, Iterations: p.Iterations}
+		if name == QueryNameSelect {
+			// 多客户端并发执行：select *
+			var errCount int64
+			run := func() {
+				var wg sync.WaitGroup
+				for j := 0; j < p.T; j++ {
+					wg.Add(1)
+					go func(worker int) {
+						defer wg.Done()
+						if err, _ := b.RunQuery(worker, QueryNameSelect); err != nil {
+							atomic.AddInt64(&errCount, 1)
+						}
+					}(j)
+				}
+				wg.Wait()
+			}
+			for i := 0; i < p.Warmup; i++ {
+				run()
+			}
+			errCount = 0
+			samples := make([]time.Duration, 0, p.Iterations)
+			for i := 0; i < p.Iterations; i++ {
+				startTime := time.Now()
+				run()
+				samples = append(samples, time.Since(startTime))
+			}
+			summary := Summarize(samples)
+			spendT := summary.Mean.Seconds()
+			fmt.Printf("'select *' (%d client concurrent query) spend time:%f s\n", p.T, spendT)
+			printSummary(summary)
+
+			queryCount := count * p.T
+			records := float64(queryCount) / spendT
+			fmt.Printf("query speed: %d/%f = %f records/second\n\n", queryCount, spendT, records)
+			qr.Clients = p.T
+			qr.Errors = int(errCount)
+			qr.Seconds = spendT
+			qr.Value = float64(queryCount)
+			qr.Latency = NewLatencyResult(summary)
+			result.Queries = append(result.Queries, qr)
+			continue
+		}
+
+		for i := 0; i < p.Warmup; i++ {
+			b.RunQuery(0, name)
+		}
+		// value 为最后一次成功执行的结果, 失败时的返回值没有意义
+		var value float64
+		var errCount int
+		samples := make([]time.Duration, 0, p.Iterations)
+		for i := 0; i < p.Iterations; i++ {
+			startTime := time.Now()
+			err, v := b.RunQuery(0, name)
+			if err != nil {
+				if name == QueryNameCount {
+					qr.Errors = errCount + 1
+					result.Queries = append(result.Queries, qr)
+					return result.finish(err)
+				}
+				errCount++
+				continue
+			}
+			samples = append(samples, time.Since(startTime))
+			value = v
+		}
+		summary := Summarize(samples)
+		if summary.Count > 0 {
+			fmt.Printf("%s spend time:%f s\n", queryLabels[name], summary.Mean.Seconds())
+		}
+		if errCount > 0 {
+			fmt.Printf("%s failed %d of %d times\n", queryLabels[name], errCount, p.Iterations)
+		}
+		printSummary(summary)
+		fmt.Printf("\n")
+		if name == QueryNameCount {
+			count = int(value)
+		}
+		qr.Errors = errCount
+		qr.Seconds = summary.Mean.Seconds()
+		qr.Value = value
+		qr.Latency = NewLatencyResult(summary)
+		result.Queries = append(result.Queries, qr)
+	}
+	return result.finish(nil)
+}
+
+// printSummary 测量多次时输出耗时分布
+func printSummary(summary LatencySummary) {
+	if summary.Count > 1 {
+		fmt.Printf("latency: %s\n", summary)
+	}
+}
+
+// runConcurrentQuery T 个客户端各执行 name 查询 Warmup+Iterations 次, 按 QPS 限速, 输出耗时分布与实际 QPS;
+// count 为表的记录数, 用于计算 select * 的读取速度。结果中的 Value 为最后一次查询的结果
+func runConcurrentQuery(b Backend, p QueryParams, name string, count int) (error, QueryResult) {
+	qr := QueryResult{Name: name, StartTime: time.Now(), Clients: p.T, Iterations: p.Iterations}
+	samples := make([][]time.Duration, p.T)
+	errs := make([]error, p.T)
+	values := make([]float64, p.T)
+	errCounts := make([]int, p.T)
+
+	// 预热不限速, 所有客户端预热完成后再开始测量
+	var wg sync.WaitGroup
+	for j := 0; j < p.T; j++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < p.Warmup; i++ {
+				b.RunQuery(worker, name)
+			}
+		}(j)
+	}
+	wg.Wait()
+
+	pace := newPacer(p.QPS)
+	startTime := time.Now()
+	for j := 0; j < p.T; j++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < p.Iterations; i++ {
+				pace.wait()
+				queryStart := time.Now()
+				err, value := b.RunQuery(worker, name)
+				if err != nil {
+					errs[worker] = err
+					errCounts[worker]++
+					continue
+				}
+				samples[worker] = append(samples[worker], time.Since(queryStart))
+				values[worker] = value
+			}
+		}(j)
+	}
+	wg.Wait()
+	spendT := time.Since(startTime).Seconds()
+
+	var all []time.Duration
+	var errCount int
+	for j := 0; j < p.T; j++ {
+		all = append(all, samples[j]...)
+		errCount += errCounts[j]
+	}
+	summary := Summarize(all)
+	label := queryLabels[name]
+	if name == QueryNameSelect {
+		label = "'select *'"
+	}
+	fmt.Printf("%s (%d client concurrent query) spend time:%f s, %d queries, %f queries/second\n",
+		label, p.T, spendT, len(all), float64(len(all))/spendT)
+	if errCount > 0 {
+		fmt.Printf("%s failed %d of %d times\n", label, errCount, p.T*p.Iterations)
+	}
+	if summary.Count > 0 {
+		fmt.Printf("latency: %s\n", summary)
+	}
+	if name == QueryNameSelect {
+		queryCount := count * len(all)
+		fmt.Printf("query speed: %d/%f = %f records/second\n", queryCount, spendT, float64(queryCount)/spendT)
+	}
+	fmt.Printf("\n")
+
+	qr.Errors = errCount
+	qr.Seconds = spendT
+	qr.QPS = float64(len(all)) / spendT
+	qr.Latency = NewLatencyResult(summary)
+	for j := 0; j < p.T; j++ {
+		if len(samples[j]) > 0 {
+			qr.Value = values[j]
+			return nil, qr
+		}
+	}
+	return errs[0], qr
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+// flakyQueryBackend sum 查询第一次返回 42, 之后都失败
+type flakyQueryBackend struct {
+	skipNullBackend
+	calls int
+}
+
+func (f *flakyQueryBackend) Queries() []string { return []string{QueryNameSum} }
+func (f *flakyQueryBackend) RunQuery(worker int, name string) (error, float64) {
+	f.calls++
+	if f.calls > 1 {
+		return errors.New("query fail"), 0
+	}
+	return nil, 42
+}
+
+func TestRunQueryKeepsLastSuccessfulValue(t *testing.T) {
+	err, r := RunQuery(&flakyQueryBackend{}, QueryParams{T: 1, Iterations: 3})
+	if err != nil || len(r.Queries) != 1 {
+		t.Fatalf("RunQuery = %v, %+v", err, r)
+	}
+	q := r.Queries[0]
+	if q.Value != 42 || q.Errors != 2 || q.Latency.Count != 1 {
+		t.Errorf("sum query value=%v errors=%d samples=%d, want 42, 2, 1", q.Value, q.Errors, q.Latency.Count)
+	}
+}
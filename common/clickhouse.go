@@ -0,0 +1,254 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// CKBackend ClickHouse 写入与查询, 写入使用原生协议的 batch, 建表和查询使用 database/sql
+type CKBackend struct {
+	conf     *DBConfig
+	mode     string
+	schema   *Schema
+	connList []driver.Conn
+	dbList   []*sql.DB
+}
+
+func NewCKBackend(conf *DBConfig, mode string, schema *Schema) *CKBackend {
+	return &CKBackend{
+		conf:   conf,
+		mode:   mode,
+		schema: schema,
+	}
+}
+
+func (c *CKBackend) Name() string {
+	return CK
+}
+
+func (c *CKBackend) Version() string {
+	_, version := QueryVersion(c.dbList[0], "select version()")
+	return version
+}
+
+// ckRetryableCodes 服务端过载、超时、网络或 Keeper 故障的异常码, 稍后重新写入可能成功:
+// TIMEOUT_EXCEEDED、TOO_MANY_SIMULTANEOUS_QUERIES、SOCKET_TIMEOUT、NETWORK_ERROR、
+// MEMORY_LIMIT_EXCEEDED、TABLE_IS_READ_ONLY、TOO_MANY_PARTS、KEEPER_EXCEPTION
+var ckRetryableCodes = map[int32]bool{159: true, 202: true, 209: true, 210: true, 241: true, 242: true, 252: true, 999: true}
+
+// Retryable 按服务端异常码判断, 其他异常如语法错误、类型不匹配不可重试
+func (c *CKBackend) Retryable(err error) bool {
+	var ex *clickhouse.Exception
+	if errors.As(err, &ex) {
+		return ckRetryableCodes[ex.Code]
+	}
+	return IsTransientError(err)
+}
+
+func (c *CKBackend) CheckArgs(p WriteParams) error {
+	return CheckMode(c.mode)
+}
+
+// tableName 按设备写入时所有客户端写同一张宽表 d0
+func (c *CKBackend) tableName(worker int) string {
+	if c.schema.Devices > 0 {
+		return Database + "." + Table
+	}
+	return Database + "." + TableName(c.mode, c.conf.TablePrefix, worker)
+}
+
+func (c *CKBackend) tableCount(T1 int) int {
+	if c.mode == Multi && c.schema.Devices == 0 {
+		return T1
+	}
+	return 1
+}
+
+func (c *CKBackend) options() *clickhouse.Options {
+	return &clickhouse.Options{
+		Addr: []string{c.conf.Host + ":" + c.conf.Port},
+		Auth: clickhouse.Auth{
+			Username: c.conf.User,
+			Password: c.conf.Password,
+		},
+		Settings: clickhouse.Settings{
+			"max_execution_time": 60,
+		},
+		DialTimeout: 5 * time.Second,
+		Compression: &clickhouse.Compression{
+			Method: clickhouse.CompressionLZ4,
+		},
+	}
+}
+
+func (c *CKBackend) Connect(T1 int) error {
+	fmt.Printf("start create db conn, count:%d\n", T1)
+	for i := 0; i < T1; i++ {
+		conn, err := clickhouse.Open(c.options())
+		if err != nil {
+			fmt.Printf("clickhouse connection[%d] failed:%v\n", i+1, err)
+			return err
+		}
+		c.connList = append(c.connList, conn)
+		c.dbList = append(c.dbList, clickhouse.OpenDB(c.options()))
+		fmt.Printf("clickhouse connection[%d] created.\n", i+1)
+	}
+	return nil
+}
+
+func (c *CKBackend) InitTable(T1 int) error {
+	db := c.dbList[0]
+	dropDatabaseSQL := fmt.Sprintf("DROP DATABASE IF EXISTS %s", Database)
+	_, err := db.Exec(dropDatabaseSQL)
+	if err != nil {
+		fmt.Printf("drop database  %s fail:%v \n", Database, err)
+		return err
+	}
+
+	ctDatabaseSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", Database)
+	_, err = db.Exec(ctDatabaseSQL)
+	if err != nil {
+		fmt.Printf("create database  %s fail:%v \n", Database, err)
+		return err
+	}
+
+	// 标签列作为普通列放在数据列之后
+	// 按设备写入时按 (device_id, ts) 排序
+	orderBy := "ts"
+	if c.schema.Devices > 0 {
+		orderBy = "(" + DeviceColumn + ", ts)"
+	}
+	ctTableTempte := "CREATE TABLE %s (`ts` DateTime(3) NOT NULL, " + ColumnDefs(CK, c.schema.AllColumns()) + ") ENGINE = MergeTree() ORDER BY " + orderBy + ";"
+	for z := 0; z < c.tableCount(T1); z++ {
+		tableName := c.tableName(z)
+		ctTableSQL := fmt.Sprintf(ctTableTempte, tableName)
+		_, err = db.Exec(ctTableSQL)
+		if err != nil {
+			fmt.Printf("create table %s fail:%v \n", tableName, err)
+			return err
+		}
+	}
+
+	fmt.Printf("Initialize database and table completed.\n")
+	return nil
+}
+
+func (c *CKBackend) TruncateTables(T1 int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for z := 0; z < c.tableCount(T1); z++ {
+		tableName := c.tableName(z)
+		delSql := fmt.Sprintf("truncate table %s", tableName)
+		if err := c.connList[0].Exec(ctx, delSql); err != nil {
+			fmt.Printf("truncate table %s fail:%v \n", tableName, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordTable 按设备写入时为宽表 d0, 否则为第 worker 个客户端写入的表
+func (c *CKBackend) RecordTable(worker int, rec Record) string {
+	return c.tableName(worker)
+}
+
+func (c *CKBackend) QueryChecksum(table string, sums bool) (error, TableChecksum) {
+	tsExpr := fmt.Sprintf("toUnixTimestamp64Milli(ts) - %d", StartTimestamp)
+	return QueryChecksum(c.dbList[0], table, tsExpr, c.schema.Columns, sums)
+}
+
+// Queried 查询测试读取 d0
+func (c *CKBackend) Queried(table string) bool {
+	return table == Database+"."+Table
+}
+
+// ckBatch 一次 PrepareBatch/Send 要写入的数据
+type ckBatch struct {
+	tableName string
+	records   []Record
+	tags      []interface{}
+}
+
+func (c *CKBackend) EncodeBatch(worker int, records []Record) (error, interface{}) {
+	return nil, &ckBatch{
+		tableName: c.tableName(worker),
+		records:   records,
+		tags:      c.schema.TableTags(c.mode, worker),
+	}
+}
+
+// ckValue 将值转换为列类型对应的 Go 类型, NULL 为 nil
+func ckValue(col Column, v interface{}) interface{} {
+	switch x := v.(type) {
+	case int64:
+		if col.Type == TypeInt {
+			return int32(x)
+		}
+	case float64:
+		if col.Type == TypeFloat {
+			return float32(x)
+		}
+	}
+	return v
+}
+
+func (c *CKBackend) WriteBatch(worker int, batch interface{}) error {
+	data := batch.(*ckBatch)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sql1 := fmt.Sprintf("INSERT INTO %s (%s) VALUES", data.tableName, c.schema.ColumnNames())
+	b, err := c.connList[worker].PrepareBatch(ctx, sql1)
+	if err != nil {
+		fmt.Printf("clickhouse PrepareBatch err:%v \n", err)
+		return err
+	}
+
+	columns := c.schema.AllColumns()
+	args := make([]interface{}, len(columns)+1)
+	for _, rec := range data.records {
+		args[0] = time.UnixMilli(rec.Ts)
+		for i, v := range c.schema.Row(rec, data.tags) {
+			args[i+1] = ckValue(columns[i], v)
+		}
+		err = b.Append(args...)
+		if err != nil {
+			fmt.Printf("clickhouse batch append err:%v \n", err)
+			return err
+		}
+	}
+
+	if err = b.Send(); err != nil {
+		fmt.Printf("send to clickhouse err:%v \n", err)
+		return err
+	}
+	return nil
+}
+
+func (c *CKBackend) Flush(worker int) error {
+	return nil
+}
+
+func (c *CKBackend) Queries() []string {
+	return []string{QueryNameCount, QueryNameSelect, QueryNamePoint, QueryNameAvg, QueryNameSum, QueryNameMax, QueryNameMin, QueryNameLatest}
+}
+
+func (c *CKBackend) RunQuery(worker int, name string) (error, float64) {
+	return RunSqlQuery(c.dbList[worker], name, Database+"."+Table, c.conf.PointQueryTsCondition, c.schema.AggColumn(), "")
+}
+
+func (c *CKBackend) Close() {
+	for _, conn := range c.connList {
+		conn.Close()
+	}
+	for _, db := range c.dbList {
+		db.Close()
+	}
+}
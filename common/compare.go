@@ -0,0 +1,98 @@
+package common
+
+import (
+	"fmt"
+)
+
+const DefaultRegressionThreshold = 10.0
+
+// Comparison 基线与本次测试的同一项指标
+type Comparison struct {
+	Metric       string
+	Base         float64
+	Current      float64
+	HigherBetter bool    // 吞吐量越大越好, 耗时越小越好
+	Change       float64 // 相对基线的变化百分比, 正数表示变好
+	Regression   bool    // 变差超过阈值
+}
+
+// CompareResults 对比 base 与 cur 的写入速度、写入请求耗时、饱和点、各项查询的耗时与 QPS,
+// 变差超过 threshold 百分比的指标标记为回退。基线中为 0 的指标不参与对比
+func CompareResults(base, cur *Result, threshold float64) []Comparison {
+	var comps []Comparison
+	add := func(metric string, b, c float64, higherBetter bool) {
+		if b <= 0 {
+			return
+		}
+		change := (c - b) / b * 100
+		if !higherBetter {
+			change = -change
+		}
+		comps = append(comps, Comparison{
+			Metric:       metric,
+			Base:         b,
+			Current:      c,
+			HigherBetter: higherBetter,
+			Change:       change,
+			Regression:   change < -threshold,
+		})
+	}
+	addLatency := func(prefix string, b, c *LatencyResult) {
+		if b == nil || c == nil {
+			return
+		}
+		add(prefix+" p50(ms)", b.P50, c.P50, false)
+		add(prefix+" p99(ms)", b.P99, c.P99, false)
+	}
+
+	add("write throughput(records/s)", base.Throughput, cur.Throughput, true)
+	addLatency("write batch latency", base.Latency, cur.Latency)
+	addLatency("write queue delay", base.QueueDelay, cur.QueueDelay)
+	addLatency("write retry latency", base.RetryLatency, cur.RetryLatency)
+	// 写入失败的记录数增加即为回退
+	if bf, cf := base.failedRows(), cur.failedRows(); cf > bf {
+		comps = append(comps, Comparison{Metric: "write failed records", Base: float64(bf), Current: float64(cf), Regression: true})
+	}
+	if base.Saturation != nil && cur.Saturation != nil {
+		add("knee point(records/s)", base.Saturation.Knee, cur.Saturation.Knee, true)
+	}
+
+	for _, bq := range base.Queries {
+		for _, cq := range cur.Queries {
+			if cq.Name != bq.Name {
+				continue
+			}
+			label := queryLabels[bq.Name]
+			if label == "" {
+				label = "'" + bq.Name + "' query"
+			}
+			if bq.QPS > 0 && cq.QPS > 0 {
+				add(label+" qps", bq.QPS, cq.QPS, true)
+			}
+			addLatency(label, bq.Latency, cq.Latency)
+			// 查询失败时耗时没有意义, 失败次数增加即为回退
+			if cq.Errors > bq.Errors {
+				comps = append(comps, Comparison{Metric: label + " errors", Base: float64(bq.Errors), Current: float64(cq.Errors), Regression: true})
+			}
+		}
+	}
+	return comps
+}
+
+// PrintComparisons 输出对比结果, 返回回退的指标数
+func PrintComparisons(base, cur *Result, comps []Comparison, threshold float64) int {
+	fmt.Printf("base:    %s %s %s version:%s label:%s start:%s\n", base.Id, base.Target, base.Command, base.Version, base.Label, base.StartTime.Format(TsLayout))
+	fmt.Printf("current: %s %s %s version:%s label:%s start:%s\n", cur.Id, cur.Target, cur.Command, cur.Version, cur.Label, cur.StartTime.Format(TsLayout))
+	fmt.Printf("%-40s %16s %16s %10s\n", "metric", "base", "current", "change")
+	var regressions int
+	for _, c := range comps {
+		flag := ""
+		if c.Regression {
+			flag = "  REGRESSION"
+			regressions++
+		}
+		fmt.Printf("%-40s %16.3f %16.3f %+9.2f%%%s\n", c.Metric, c.Base, c.Current, c.Change, flag)
+	}
+	fmt.Printf("%d metrics compared, %d regressions beyond %.2f%%\n", len(comps), regressions, threshold)
+	return regressions
+}
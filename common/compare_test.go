@@ -0,0 +1,163 @@
+package common
+
+import (
+	"math"
+	"testing"
+)
+
+// findComparison 返回名为 metric 的对比结果, 不存在时返回 nil
+func findComparison(comps []Comparison, metric string) *Comparison {
+	for i := range comps {
+		if comps[i].Metric == metric {
+			return &comps[i]
+		}
+	}
+	return nil
+}
+
+func TestCompareResultsThreshold(t *testing.T) {
+	tests := []struct {
+		name       string
+		metric     string
+		base, cur  *Result
+		change     float64
+		regression bool
+	}{
+		{
+			name:   "throughput improved",
+			metric: "write throughput(records/s)",
+			base:   &Result{Throughput: 1000}, cur: &Result{Throughput: 1200},
+			change: 20,
+		},
+		{
+			name:   "throughput dropped within threshold",
+			metric: "write throughput(records/s)",
+			base:   &Result{Throughput: 1000}, cur: &Result{Throughput: 900},
+			change: -10,
+		},
+		{
+			name:   "throughput dropped beyond threshold",
+			metric: "write throughput(records/s)",
+			base:   &Result{Throughput: 1000}, cur: &Result{Throughput: 899},
+			change: -10.1, regression: true,
+		},
+		{
+			name:   "latency increased beyond threshold",
+			metric: "write batch latency p99(ms)",
+			base:   &Result{Latency: &LatencyResult{P50: 1, P99: 10}}, cur: &Result{Latency: &LatencyResult{P50: 1, P99: 12}},
+			change: -20, regression: true,
+		},
+		{
+			name:   "latency decreased",
+			metric: "write batch latency p50(ms)",
+			base:   &Result{Latency: &LatencyResult{P50: 2, P99: 10}}, cur: &Result{Latency: &LatencyResult{P50: 1, P99: 10}},
+			change: 50,
+		},
+		{
+			name:   "knee point dropped",
+			metric: "knee point(records/s)",
+			base:   &Result{Saturation: &SaturationResult{Knee: 10000}}, cur: &Result{Saturation: &SaturationResult{Knee: 5000}},
+			change: -50, regression: true,
+		},
+		{
+			name:   "query qps dropped",
+			metric: "'sum' query qps",
+			base:   &Result{Queries: []QueryResult{{Name: QueryNameSum, QPS: 100}}},
+			cur:    &Result{Queries: []QueryResult{{Name: QueryNameSum, QPS: 80}}},
+			change: -20, regression: true,
+		},
+		{
+			name:   "query latency of an unlabeled query",
+			metric: "'select' query p99(ms)",
+			base:   &Result{Queries: []QueryResult{{Name: QueryNameSelect, Latency: &LatencyResult{P50: 5, P99: 5}}}},
+			cur:    &Result{Queries: []QueryResult{{Name: QueryNameSelect, Latency: &LatencyResult{P50: 5, P99: 5.25}}}},
+			change: -5,
+		},
+	}
+	for _, tt := range tests {
+		c := findComparison(CompareResults(tt.base, tt.cur, DefaultRegressionThreshold), tt.metric)
+		if c == nil {
+			t.Errorf("%s: metric %s not compared", tt.name, tt.metric)
+			continue
+		}
+		if math.Abs(c.Change-tt.change) > 1e-9 || c.Regression != tt.regression {
+			t.Errorf("%s: change %.3f%% regression %v, want %.3f%% %v", tt.name, c.Change, c.Regression, tt.change, tt.regression)
+		}
+	}
+}
+
+func TestCompareResultsSkipsMissingMetrics(t *testing.T) {
+	base := &Result{Latency: &LatencyResult{P50: 1, P99: 2}, Queries: []QueryResult{{Name: QueryNameCount, QPS: 10}}}
+	cur := &Result{Throughput: 1000, Queries: []QueryResult{{Name: QueryNameAvg, QPS: 10}}}
+	// 基线中为 0 的指标、只有一方有的耗时与查询都不参与对比
+	if comps := CompareResults(base, cur, DefaultRegressionThreshold); len(comps) != 0 {
+		t.Errorf("CompareResults = %+v, want nothing compared", comps)
+	}
+}
+
+func TestCompareResultsFailures(t *testing.T) {
+	base := &Result{Throughput: 1000, Rounds: []RoundResult{{FailedRows: 10}},
+		Queries: []QueryResult{{Name: QueryNameCount, Errors: 1}}}
+	cur := &Result{Throughput: 1000, Rounds: []RoundResult{{FailedRows: 5}, {FailedRows: 10}},
+		Queries: []QueryResult{{Name: QueryNameCount, Errors: 2}}}
+	comps := CompareResults(base, cur, DefaultRegressionThreshold)
+	if c := findComparison(comps, "write failed records"); c == nil || !c.Regression || c.Base != 10 || c.Current != 15 {
+		t.Errorf("failed records comparison %+v, want regression from 10 to 15", c)
+	}
+	if c := findComparison(comps, "'count(*)' query errors"); c == nil || !c.Regression {
+		t.Errorf("query errors comparison %+v, want regression", c)
+	}
+
+	// 失败减少不是回退, 也不输出
+	comps = CompareResults(cur, base, DefaultRegressionThreshold)
+	if c := findComparison(comps, "write failed records"); c != nil {
+		t.Errorf("failed records comparison %+v, want none", c)
+	}
+	if c := findComparison(comps, "'count(*)' query errors"); c != nil {
+		t.Errorf("query errors comparison %+v, want none", c)
+	}
+}
+
+func TestFindRun(t *testing.T) {
+	history := []*Result{
+		{Id: "1", Target: MO, Command: "write", Label: "nightly"},
+		{Id: "2", Target: CK, Command: "write", Label: "nightly"},
+		{Id: "3", Target: MO, Command: "query"},
+		{Id: "4", Target: MO, Command: "write", Label: "nightly"},
+		{Id: "5", Target: MO, Command: "write", Label: "v1.2"},
+		{Id: "6", Target: MO, Command: "write"},
+	}
+	tests := []struct {
+		ref  string
+		like *Result
+		want string // 空表示找不到
+	}{
+		{"latest", nil, "6"},
+		{"previous", nil, "5"},
+		{"3", nil, "3"},
+		{"nightly", nil, "4"},
+		{"v1.2", nil, "5"},
+		{"unknown", nil, ""},
+		// like 时只在 like 之前的同一数据库、同一子命令的结果中查找
+		{"latest", history[4], "4"},
+		{"previous", history[4], "4"},
+		{"nightly", history[4], "4"},
+		{"nightly", history[3], "1"},
+		{"v1.2", history[3], ""},
+		{"2", history[3], ""},
+		{"latest", history[0], ""},
+		// like 不在历史中时在全部同类结果中查找
+		{"latest", &Result{Target: CK, Command: "write"}, "2"},
+		{"previous", &Result{Target: MO, Command: "query"}, "3"},
+	}
+	for _, tt := range tests {
+		err, r := FindRun(history, tt.ref, tt.like)
+		var got string
+		if r != nil {
+			got = r.Id
+		}
+		if got != tt.want || (err == nil) != (tt.want != "") {
+			t.Errorf("FindRun(%s, like %v) = %s, %v, want %s", tt.ref, tt.like, got, err, tt.want)
+		}
+	}
+}
@@ -5,6 +5,52 @@ const (
 	MO       = "MO"
 	TDengine = "TDengine"
 	CK       = "CK"
+	SR       = "SR"
 	Database = "test" //数据库名
 	Table    = "d0"
+
+	DeviceColumn = "device_id" // 按设备写入宽表时的设备列名, InfluxDB 中为 tag 名
+)
+
+// 写入模式: multi 为每个客户端写一张表, single 为所有客户端写同一张表 d0
+const (
+	Multi  = "multi"
+	Single = "single"
+)
+
+// MO 表类型(tType)与写入方式(wType)
+const (
+	Ts       = "ts"
+	TsPK     = "tsPK"
+	IntPK    = "intPK"
+	Insert   = "insert"
+	LoadLine = "loadLine"
+	LoadFile = "loadFile"
+	Prepare  = "prepare"
+)
+
+// TDengine 写入方式(wType): sql 语句、参数绑定、InfluxDB 行协议与 OpenTSDB telnet 协议的无模式写入
+const (
+	Sql    = "sql"
+	Stmt   = "stmt"
+	Line   = "line"
+	Telnet = "telnet"
+)
+
+// 查询测试项名称
+const (
+	QueryNameCount      = "count"
+	QueryNameSelect     = "select"
+	QueryNamePoint      = "point"
+	QueryNameAvg        = "avg"
+	QueryNameSum        = "sum"
+	QueryNameMax        = "max"
+	QueryNameMin        = "min"
+	QueryNameTimeWindow = "timeWindow"
+	QueryNameLatest     = "latest" // 最新一条记录的值
+)
+
+const (
+	StartTimestamp int64 = 1500000000000
+	TsLayout             = "2006-01-02 15:04:05.000"
 )
@@ -0,0 +1,221 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// QueriedBackend 由支持校验查询结果的数据库实现, 与 VerifyBackend.RecordTable 一起确定查询测试读取哪些记录
+type QueriedBackend interface {
+	VerifyBackend
+	// Queried 表 table(RecordTable 的返回值)中的记录是否由查询测试读取
+	Queried(table string) bool
+}
+
+// Aggregates 查询测试读取的记录中 Column 列的聚合值, 在写入时由客户端计算, 只统计写入成功的记录
+type Aggregates struct {
+	Column   string   `json:"column"`
+	Count    int64    `json:"count"`  // 记录数
+	Values   int64    `json:"values"` // Column 不为 NULL 的记录数
+	Sum      float64  `json:"sum"`
+	Max      float64  `json:"max"`
+	Min      float64  `json:"min"`
+	LatestTs int64    `json:"latestTs"`
+	Latest   *float64 `json:"latest,omitempty"` // 最新一条记录的值, 为 NULL 或有多条最新记录(如按设备写入)时为空
+	Abs      float64  `json:"abs"`              // 各值绝对值之和, 用于确定 sum、avg 比较的容差
+
+	index int // Column 在 Schema.Columns 中的位置
+}
+
+// newAggregates 表结构中没有数值列时返回 nil
+func newAggregates(schema *Schema) *Aggregates {
+	column := schema.AggColumn()
+	if column == "" {
+		return nil
+	}
+	a := &Aggregates{Column: column}
+	for i, c := range schema.Columns {
+		if c.Name == column {
+			a.index = i
+		}
+	}
+	return a
+}
+
+func (a *Aggregates) add(rec Record) {
+	a.Count++
+	var value *float64
+	switch v := rec.Values[a.index].(type) {
+	case int64:
+		f := float64(v)
+		value = &f
+	case float64:
+		value = &v
+	}
+	switch {
+	case a.Count == 1 || rec.Ts > a.LatestTs:
+		a.LatestTs = rec.Ts
+		a.Latest = value
+	case rec.Ts == a.LatestTs:
+		a.Latest = nil
+	}
+	if value == nil {
+		return
+	}
+	a.addValue(*value)
+}
+
+func (a *Aggregates) addValue(v float64) {
+	if a.Values == 0 || v > a.Max {
+		a.Max = v
+	}
+	if a.Values == 0 || v < a.Min {
+		a.Min = v
+	}
+	a.Values++
+	a.Sum += v
+	a.Abs += math.Abs(v)
+}
+
+func (a *Aggregates) merge(o *Aggregates) {
+	if o == nil || o.Count == 0 {
+		return
+	}
+	switch {
+	case a.Count == 0 || o.LatestTs > a.LatestTs:
+		a.LatestTs = o.LatestTs
+		a.Latest = o.Latest
+	case o.LatestTs == a.LatestTs:
+		a.Latest = nil
+	}
+	a.Count += o.Count
+	if o.Values > 0 {
+		if a.Values == 0 || o.Max > a.Max {
+			a.Max = o.Max
+		}
+		if a.Values == 0 || o.Min < a.Min {
+			a.Min = o.Min
+		}
+	}
+	a.Values += o.Values
+	a.Sum += o.Sum
+	a.Abs += o.Abs
+}
+
+// expect 返回查询项 name 的期望值与容差, 不能校验的查询项返回 false。
+// 数据库以 float 存储的值只有约 7 位有效数字, 聚合值按相对误差 1e-6 比较
+func (a *Aggregates) expect(name string) (expected, tolerance float64, ok bool) {
+	switch name {
+	case QueryNameCount:
+		return float64(a.Count), 0, true
+	case QueryNameSum:
+		return a.Sum, 1e-6*a.Abs + 1e-6, a.Values > 0
+	case QueryNameAvg:
+		if a.Values == 0 {
+			return 0, 0, false
+		}
+		return a.Sum / float64(a.Values), 1e-6*a.Abs/float64(a.Values) + 1e-6, true
+	case QueryNameMax:
+		return a.Max, 1e-6*math.Abs(a.Max) + 1e-6, a.Values > 0
+	case QueryNameMin:
+		return a.Min, 1e-6*math.Abs(a.Min) + 1e-6, a.Values > 0
+	case QueryNameLatest:
+		if a.Latest == nil {
+			return 0, 0, false
+		}
+		return *a.Latest, 1e-6*math.Abs(*a.Latest) + 1e-6, true
+	}
+	return 0, 0, false
+}
+
+// QueryCheck 一项查询的结果与写入时计算的期望值的比较
+type QueryCheck struct {
+	Expected  float64 `json:"expected"`
+	Tolerance float64 `json:"tolerance"`
+	OK        bool    `json:"ok"`
+}
+
+// CheckQueries 将查询测试 r 的 count、sum、avg、max、min、latest 的结果与写入时计算的期望值比较,
+// 结果记录在各项查询的 Check 中, 有不一致时返回错误并记入 r.Errors
+func CheckQueries(r *Result, expected *Aggregates) error {
+	if expected == nil {
+		err := errors.New(fmt.Sprintf("%s has no expected aggregates, write with -check first", r.Target))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	var failed []string
+	for i := range r.Queries {
+		q := &r.Queries[i]
+		want, tolerance, ok := expected.expect(q.Name)
+		if !ok || q.Latency == nil || q.Latency.Count == 0 {
+			continue
+		}
+		q.Check = &QueryCheck{Expected: want, Tolerance: tolerance, OK: math.Abs(q.Value-want) <= tolerance}
+		if !q.Check.OK {
+			failed = append(failed, fmt.Sprintf("%s returned %v, expected %v", q.Name, q.Value, want))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	err := errors.New(fmt.Sprintf("%s query results of column %s are incorrect: %s", r.Target, expected.Column, strings.Join(failed, "; ")))
+	fmt.Printf("%v\n", err)
+	r.Errors = append(r.Errors, err.Error())
+	return err
+}
+
+// PrintCorrectness 输出查询结果的正确性矩阵: 每行一项查询, 每列一个数据库的查询测试, 只输出校验过的结果
+func PrintCorrectness(results []*Result) {
+	var checked []*Result
+	var names []string
+	seen := map[string]bool{}
+	for _, r := range results {
+		var has bool
+		for _, q := range r.Queries {
+			if q.Check == nil {
+				continue
+			}
+			has = true
+			if !seen[q.Name] {
+				seen[q.Name] = true
+				names = append(names, q.Name)
+			}
+		}
+		if has {
+			checked = append(checked, r)
+		}
+	}
+	if len(checked) == 0 {
+		return
+	}
+
+	fmt.Printf("======== query correctness ========\n")
+	fmt.Printf("%-12s", "query")
+	for _, r := range checked {
+		fmt.Printf(" %24s", r.Target)
+	}
+	fmt.Printf("\n")
+	for _, name := range names {
+		cells := make([]string, len(checked))
+		for i, r := range checked {
+			cells[i] = "-"
+			for _, q := range r.Queries {
+				if q.Name != name || q.Check == nil {
+					continue
+				}
+				cells[i] = "ok"
+				if !q.Check.OK {
+					// 实际值/期望值
+					cells[i] = fmt.Sprintf("FAIL %.6g/%.6g", q.Value, q.Check.Expected)
+				}
+			}
+		}
+		fmt.Printf("%-12s", name)
+		for _, c := range cells {
+			fmt.Printf(" %24s", c)
+		}
+		fmt.Printf("\n")
+	}
+}
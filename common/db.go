@@ -4,17 +4,22 @@ import (
 	"database/sql"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
-	"log"
+	"strings"
 	"time"
 )
 
 func GetDbConn(T1 int, dsn string) (error, []*sql.DB) {
+	return GetDriverConn(T1, "mysql", dsn)
+}
+
+// GetDriverConn 使用指定的 database/sql 驱动创建 T1 个数据库连接
+func GetDriverConn(T1 int, driverName, dsn string) (error, []*sql.DB) {
 	var dbList []*sql.DB
 
 	fmt.Printf("start create db conn, count:%d\n", T1)
 
 	for i := 0; i < T1; i++ {
-		db, err := sql.Open("mysql", dsn) // Set database connection
+		db, err := sql.Open(driverName, dsn) // Set database connection
 		if err != nil {
 			fmt.Println("open database fail:", err)
 			return err, dbList
@@ -47,61 +52,97 @@ func ExecSql(db *sql.DB, sql1 string) error {
 	return nil
 }
 
-func ExecQuery(db *sql.DB) {
-	rows, err := db.Query(fmt.Sprintf("select * from %s", Database+"."+Table))
+// scanRow 读取当前行的全部列, 列数与类型由结果集决定
+func scanRow(rows *sql.Rows, values []interface{}) error {
+	ptrs := make([]interface{}, len(values))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	return rows.Scan(ptrs...)
+}
+
+// ExecQuery 执行 select * 全表查询, 返回读取的行数
+func ExecQuery(db *sql.DB, tableName string) (error, int) {
+	var count int
+	rows, err := db.Query(fmt.Sprintf("select * from %s", tableName))
 	if err != nil {
 		fmt.Println(err)
-		return
+		return err, count
 	}
-	var (
-		ts      time.Time
-		current float32
-		voltage int
-		phase   float32
-	)
 
 	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		fmt.Println(err)
+		return err, count
+	}
+	values := make([]interface{}, len(columns))
 	for rows.Next() {
-		err = rows.Scan(&ts, &current, &voltage, &phase)
+		err = scanRow(rows, values)
 		if err != nil {
-			log.Fatalln("scan error:\n", err)
-			return
+			fmt.Println("scan error:", err)
+			return err, count
 		}
-		//fmt.Println(ts, current, voltage, phase)
+		count++
 	}
+	return rows.Err(), count
 }
 
-func PointQuery(db *sql.DB, tsCondition string) {
+func PointQuery(db *sql.DB, tableName, tsCondition string) (error, int) {
+	var count int
 	// "select * from %s where ts='2015-01-25 01:45:56'"
-	sql1 := fmt.Sprintf("select * from %s where ts=%s", Database+"."+Table, tsCondition)
+	sql1 := fmt.Sprintf("select * from %s where ts=%s", tableName, tsCondition)
 	fmt.Printf(" point query sql: %s\n", sql1)
 	rows, err := db.Query(sql1)
 	if err != nil {
 		fmt.Println(err)
-		return
+		return err, count
 	}
 
-	var (
-		ts      time.Time
-		current float32
-		voltage int
-		phase   float32
-	)
-
 	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		fmt.Println(err)
+		return err, count
+	}
+	values := make([]interface{}, len(columns))
 	for rows.Next() {
-		err = rows.Scan(&ts, &current, &voltage, &phase)
+		err = scanRow(rows, values)
 		if err != nil {
-			log.Fatalln("scan error:\n", err)
-			return
+			fmt.Println("scan error:", err)
+			return err, count
+		}
+		count++
+		fmt.Println(" point query result: <", formatRow(values), ">")
+	}
+	return rows.Err(), count
+}
+
+// formatRow 将查询结果的一行格式化为文本, []byte 按字符串输出
+func formatRow(values []interface{}) string {
+	var fields []string
+	for _, v := range values {
+		if b, ok := v.([]byte); ok {
+			v = string(b)
 		}
-		fmt.Println(" point query result: <", ts, current, voltage, phase, ">")
+		fields = append(fields, fmt.Sprint(v))
 	}
+	return strings.Join(fields, " ")
 }
 
-func QueryCount(db *sql.DB) (error, int) {
+// QueryVersion 执行 sql1 查询数据库版本, 如 select version()
+func QueryVersion(db *sql.DB, sql1 string) (error, string) {
+	var version string
+	if err := db.QueryRow(sql1).Scan(&version); err != nil {
+		fmt.Printf("query version fail:%v\n", err)
+		return err, version
+	}
+	return nil, version
+}
+
+func QueryCount(db *sql.DB, tableName string) (error, int) {
 	var count int
-	rows, err := db.Query(fmt.Sprintf("select count(*) from %s", Database+"."+Table))
+	rows, err := db.Query(fmt.Sprintf("select count(*) from %s", tableName))
 	if err != nil {
 		fmt.Println(err)
 		return err, count
@@ -111,94 +152,165 @@ func QueryCount(db *sql.DB) (error, int) {
 	for rows.Next() {
 		err = rows.Scan(&count)
 		if err != nil {
-			log.Fatalln("scan error:\n", err)
+			fmt.Println("scan error:", err)
 			return err, count
 		}
 		fmt.Printf("\n count value is:%d\n", count)
 	}
-	return nil, count
+	return rows.Err(), count
+}
+
+func QueryAvg(db *sql.DB, tableName, column string) (error, float64) {
+	return queryAgg(db, "avg", tableName, column)
+}
+
+func QuerySum(db *sql.DB, tableName, column string) (error, float64) {
+	return queryAgg(db, "sum", tableName, column)
 }
 
-func QueryAvg(db *sql.DB) {
-	rows, err := db.Query(fmt.Sprintf("select avg(`current`) from %s", Database+"."+Table))
+func QueryMax(db *sql.DB, tableName, column string) (error, float64) {
+	return queryAgg(db, "max", tableName, column)
+}
+
+func QueryMin(db *sql.DB, tableName, column string) (error, float64) {
+	return queryAgg(db, "min", tableName, column)
+}
+
+// queryAgg 查询 column 字段的聚合值, fn 为 avg|sum|max|min; 没有记录或全部为 NULL 时结果为 NULL, 返回 0
+func queryAgg(db *sql.DB, fn, tableName, column string) (error, float64) {
+	var value sql.NullFloat64
+	rows, err := db.Query(fmt.Sprintf("select %s(`%s`) from %s", fn, column, tableName))
 	if err != nil {
 		fmt.Println(err)
-		return
+		return err, 0
 	}
 
 	defer rows.Close()
 	for rows.Next() {
-		var (
-			avg float64
-		)
-		err = rows.Scan(&avg)
+		err = rows.Scan(&value)
 		if err != nil {
-			log.Fatalln("scan error:\n", err)
-			return
+			fmt.Println("scan error:", err)
+			return err, 0
 		}
-		fmt.Println(" avg value is:", avg)
+		fmt.Printf(" %s value is: %v\n", fn, value.Float64)
 	}
+	return rows.Err(), value.Float64
 }
 
-func QuerySum(db *sql.DB) {
-	rows, err := db.Query(fmt.Sprintf("select sum(`current`) from %s", Database+"."+Table))
+// QueryLatest 查询最新一条记录 column 字段的值
+func QueryLatest(db *sql.DB, tableName, column string) (error, float64) {
+	var value sql.NullFloat64
+	err := db.QueryRow(fmt.Sprintf("select `%s` from %s order by ts desc limit 1", column, tableName)).Scan(&value)
 	if err != nil {
 		fmt.Println(err)
-		return
+		return err, 0
 	}
+	fmt.Printf(" latest value is: %v\n", value.Float64)
+	return nil, value.Float64
+}
 
-	defer rows.Close()
-	for rows.Next() {
-		var (
-			sum float64
-		)
-		err = rows.Scan(&sum)
-		if err != nil {
-			log.Fatalln("scan error:\n", err)
-			return
+// QueryChecksum 查询表的记录数, sums 为 true 时同时查询 tsExpr 与 columns 中各数值列之和。
+// tsExpr 为 ts 相对 StartTimestamp 的毫秒数的表达式, 为空表示不校验 ts
+func QueryChecksum(db *sql.DB, tableName, tsExpr string, columns []Column, sums bool) (error, TableChecksum) {
+	checksum := TableChecksum{Sums: map[string]float64{}}
+	exprs := []string{"count(*)"}
+	var names []string
+	if sums {
+		if tsExpr != "" {
+			exprs = append(exprs, fmt.Sprintf("sum(%s)", tsExpr))
+			names = append(names, TsChecksumColumn)
 		}
-		fmt.Println(" sum value is:", sum)
+		for _, c := range columns {
+			if c.IsNumeric() {
+				exprs = append(exprs, fmt.Sprintf("sum(`%s`)", c.Name))
+				names = append(names, c.Name)
+			}
+		}
+	}
+
+	values := make([]sql.NullFloat64, len(names))
+	dest := []interface{}{&checksum.Rows}
+	for i := range values {
+		dest = append(dest, &values[i])
 	}
+	sql1 := fmt.Sprintf("select %s from %s", strings.Join(exprs, ", "), tableName)
+	if err := db.QueryRow(sql1).Scan(dest...); err != nil {
+		fmt.Printf("query checksum of %s fail:%v\n", tableName, err)
+		return err, checksum
+	}
+	// 没有记录或全部为 NULL 时之和为 NULL, 按 0 比较
+	for i, name := range names {
+		checksum.Sums[name] = values[i].Float64
+	}
+	return nil, checksum
 }
 
-func QueryMax(db *sql.DB) {
-	rows, err := db.Query(fmt.Sprintf("select max(`current`) from %s", Database+"."+Table))
+// QueryTimeWindow 执行时间窗口查询, 返回窗口数; 窗口内全部为 NULL 时 max、min 为 NULL
+func QueryTimeWindow(db *sql.DB, sql1 string) (error, int) {
+	var count int
+	fmt.Printf("TimeWindow query sql:%s\n", sql1)
+	rows, err := db.Query(sql1)
 	if err != nil {
 		fmt.Println(err)
-		return
+		return err, count
 	}
 
 	defer rows.Close()
 	for rows.Next() {
 		var (
-			max1 float64
+			_wstart time.Time
+			_wend   time.Time
+			max1    sql.NullFloat64
+			min1    sql.NullFloat64
 		)
-		err = rows.Scan(&max1)
+		err = rows.Scan(&_wstart, &_wend, &max1, &min1)
 		if err != nil {
-			log.Fatalln("scan error:\n", err)
-			return
+			fmt.Println("scan error:", err)
+			return err, count
 		}
-		fmt.Println(" max value is:", max1)
+		count++
+		fmt.Println(_wstart, _wend, max1.Float64, min1.Float64)
 	}
+	return rows.Err(), count
 }
 
-func QueryMin(db *sql.DB) {
-	rows, err := db.Query(fmt.Sprintf("select min(`current`) from %s", Database+"."+Table))
-	if err != nil {
-		fmt.Println(err)
-		return
+// RunSqlQuery 通过 database/sql 执行名为 name 的查询测试项, aggColumn 为聚合查询的列,
+// aggColumn、timeWindowSql 为空表示不支持聚合、时间窗口查询
+func RunSqlQuery(db *sql.DB, name, tableName, tsCondition, aggColumn, timeWindowSql string) (error, float64) {
+	var err error
+	var value float64
+	var count int
+	if aggColumn == "" && (name == QueryNameAvg || name == QueryNameSum || name == QueryNameMax || name == QueryNameMin || name == QueryNameLatest) {
+		return fmt.Errorf("unsupported query:%s, schema has no numeric column", name), value
 	}
-
-	defer rows.Close()
-	for rows.Next() {
-		var (
-			min1 float64
-		)
-		err = rows.Scan(&min1)
-		if err != nil {
-			log.Fatalln("scan error:\n", err)
-			return
+	switch name {
+	case QueryNameCount:
+		err, count = QueryCount(db, tableName)
+		value = float64(count)
+	case QueryNameSelect:
+		err, count = ExecQuery(db, tableName)
+		value = float64(count)
+	case QueryNamePoint:
+		err, count = PointQuery(db, tableName, tsCondition)
+		value = float64(count)
+	case QueryNameAvg:
+		err, value = QueryAvg(db, tableName, aggColumn)
+	case QueryNameSum:
+		err, value = QuerySum(db, tableName, aggColumn)
+	case QueryNameMax:
+		err, value = QueryMax(db, tableName, aggColumn)
+	case QueryNameMin:
+		err, value = QueryMin(db, tableName, aggColumn)
+	case QueryNameLatest:
+		err, value = QueryLatest(db, tableName, aggColumn)
+	case QueryNameTimeWindow:
+		if timeWindowSql == "" {
+			return fmt.Errorf("unsupported query:%s", name), value
 		}
-		fmt.Println(" min value is:", min1)
+		err, count = QueryTimeWindow(db, timeWindowSql)
+		value = float64(count)
+	default:
+		err = fmt.Errorf("unsupported query:%s", name)
 	}
+	return err, value
 }
@@ -113,7 +113,7 @@ func (c *ConfigFile) GetString(section string, option string) (string, error) {
 			errors.New(
 				fmt.Sprintf(
 					"Possible cycle while unfolding variables: max depth of %d reached",
-					strconv.Itoa(DepthValues),
+					DepthValues,
 				),
 			)
 	}
@@ -208,7 +208,12 @@ func ReadSRFile(path string) (*SRConfig, error) {
 	srConfig := NewSRConfig()
 	var err error
 
-	confFile, _ := ReadConfigFile(path)
+	confFile, err := ReadConfigFile(path)
+	if err != nil {
+		fmt.Printf("read config file fail, err:%v\n", err)
+		return srConfig, err
+	}
+
 	srConfig.Host, err = confFile.GetString("dbInfo", "host")
 	if err != nil || len(srConfig.Host) <= 0 {
 		fmt.Printf("load config [dbInfo:host] failed: host[%s], err[%v]\n", srConfig.Host, err)
@@ -0,0 +1,283 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultSeed int64 = 42
+
+// 取值模型名称
+const (
+	ModelUniform  = "uniform"  // 均匀分布: min, max
+	ModelWalk     = "walk"     // 随机游走: start, step, min, max
+	ModelSine     = "sine"     // 正弦周期 + 正态噪声: base, amp, period(行数), noise
+	ModelStep     = "step"     // 阶梯: start, delta, every(行数)
+	ModelConstant = "constant" // 常量: value
+	ModelCounter  = "counter"  // 计数器: start, inc, max(大于 0 时回绕到 start)
+)
+
+// 各模型支持的参数及默认值
+var modelParams = map[string]map[string]float64{
+	ModelUniform:  {"min": 0, "max": 1},
+	ModelWalk:     {"start": 0, "step": 0.1, "min": -math.MaxFloat64, "max": math.MaxFloat64},
+	ModelSine:     {"base": 0, "amp": 1, "period": 3600, "noise": 0},
+	ModelStep:     {"start": 0, "delta": 1, "every": 1000},
+	ModelConstant: {"value": 0},
+	ModelCounter:  {"start": 0, "inc": 1, "max": 0},
+}
+
+// ValueModel 生成一列数据的取值, 每个客户端独享一个实例, 可以保存状态
+type ValueModel interface {
+	Next(rng *rand.Rand) float64
+}
+
+// ModelSpec 一列数据的取值模型及参数
+type ModelSpec struct {
+	Name   string
+	Params map[string]float64
+}
+
+func (m ModelSpec) String() string {
+	keys := make([]string, 0, len(m.Params))
+	for k := range m.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		params = append(params, k+"="+strconv.FormatFloat(m.Params[k], 'g', -1, 64))
+	}
+	return m.Name + ":" + strings.Join(params, ",")
+}
+
+// New 创建一个新的取值模型实例
+func (m ModelSpec) New() ValueModel {
+	p := m.Params
+	switch m.Name {
+	case ModelWalk:
+		return &walkModel{value: p["start"], step: p["step"], min: p["min"], max: p["max"]}
+	case ModelSine:
+		return &sineModel{base: p["base"], amp: p["amp"], period: p["period"], noise: p["noise"]}
+	case ModelStep:
+		return &stepModel{value: p["start"], delta: p["delta"], every: int64(p["every"])}
+	case ModelConstant:
+		return &stepModel{value: p["value"]}
+	case ModelCounter:
+		return &counterModel{start: p["start"], value: p["start"], inc: p["inc"], max: p["max"]}
+	default:
+		return &uniformModel{min: p["min"], max: p["max"]}
+	}
+}
+
+// ParseModelSpec 解析 model:k=v,k=v 格式的模型定义, 未指定的参数取默认值
+func ParseModelSpec(s string) (ModelSpec, error) {
+	name, args, _ := strings.Cut(strings.TrimSpace(s), ":")
+	defaults, ok := modelParams[name]
+	if !ok {
+		return ModelSpec{}, errors.New(fmt.Sprintf("unrecognized value model:%s, required to be uniform|walk|sine|step|constant|counter", name))
+	}
+
+	spec := ModelSpec{Name: name, Params: map[string]float64{}}
+	for k, v := range defaults {
+		spec.Params[k] = v
+	}
+	if args == "" {
+		return spec, nil
+	}
+	for _, kv := range strings.Split(args, ",") {
+		k, v, _ := strings.Cut(kv, "=")
+		k = strings.TrimSpace(k)
+		if _, ok = defaults[k]; !ok {
+			return spec, errors.New(fmt.Sprintf("unrecognized parameter %s of value model %s", k, name))
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return spec, errors.New(fmt.Sprintf("invalid parameter %s of value model %s: %v", k, name, err))
+		}
+		spec.Params[k] = value
+	}
+	if name == ModelSine && spec.Params["period"] <= 0 || name == ModelStep && spec.Params["every"] <= 0 {
+		return spec, errors.New(fmt.Sprintf("period/every of value model %s must be greater than 0", name))
+	}
+	return spec, nil
+}
+
+type uniformModel struct {
+	min, max float64
+}
+
+func (m *uniformModel) Next(rng *rand.Rand) float64 {
+	return m.min + rng.Float64()*(m.max-m.min)
+}
+
+type walkModel struct {
+	value, step, min, max float64
+}
+
+func (m *walkModel) Next(rng *rand.Rand) float64 {
+	m.value += (rng.Float64()*2 - 1) * m.step
+	m.value = math.Max(m.min, math.Min(m.max, m.value))
+	return m.value
+}
+
+type sineModel struct {
+	base, amp, period, noise float64
+	i                        int64
+}
+
+func (m *sineModel) Next(rng *rand.Rand) float64 {
+	v := m.base + m.amp*math.Sin(2*math.Pi*float64(m.i)/m.period)
+	if m.noise != 0 {
+		v += rng.NormFloat64() * m.noise
+	}
+	m.i++
+	return v
+}
+
+type stepModel struct {
+	value, delta float64
+	every, i     int64
+}
+
+func (m *stepModel) Next(rng *rand.Rand) float64 {
+	if m.every > 0 && m.i > 0 && m.i%m.every == 0 {
+		m.value += m.delta
+	}
+	m.i++
+	return m.value
+}
+
+type counterModel struct {
+	start, value, inc, max float64
+}
+
+func (m *counterModel) Next(rng *rand.Rand) float64 {
+	v := m.value
+	m.value += m.inc
+	if m.max > 0 && m.value > m.max {
+		m.value = m.start
+	}
+	return v
+}
+
+// GeneratorSpec 测试表结构(含各列的取值模型)与随机种子, 相同的 GeneratorSpec 对所有数据库生成完全相同的数据
+type GeneratorSpec struct {
+	Seed   int64
+	Schema *Schema
+}
+
+// DefaultGeneratorSpec 默认表结构: current 取 -3~3、voltage 取 0~19、phase 取 -1~0 的均匀分布
+func DefaultGeneratorSpec() *GeneratorSpec {
+	return &GeneratorSpec{
+		Seed:   DefaultSeed,
+		Schema: DefaultSchema(),
+	}
+}
+
+// ParseGeneratorSpec 修改 schema 中各列的取值模型, 格式为 column=model:k=v,k=v;column=..., 如
+// current=sine:base=0,amp=3,period=1440,noise=0.1;voltage=counter:start=0,inc=1,max=19
+// 未指定的列使用 schema 中定义的模型
+func ParseGeneratorSpec(s string, seed int64, schema *Schema) (*GeneratorSpec, error) {
+	spec := &GeneratorSpec{Seed: seed, Schema: schema}
+	for _, item := range strings.Split(s, ";") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		column, model, ok := strings.Cut(item, "=")
+		if !ok {
+			return spec, errors.New(fmt.Sprintf("invalid column generator:%s, required to be column=model:k=v,...", item))
+		}
+		m, err := ParseModelSpec(model)
+		if err != nil {
+			return spec, err
+		}
+		if err = schema.SetModel(strings.TrimSpace(column), m); err != nil {
+			return spec, err
+		}
+	}
+	return spec, nil
+}
+
+func (g *GeneratorSpec) String() string {
+	return fmt.Sprintf("seed=%d, %s", g.Seed, g.Schema)
+}
+
+// NewGenerator 创建第 worker 个客户端的数据生成器, 随机种子为 Seed+worker
+func (g *GeneratorSpec) NewGenerator(worker int) *Generator {
+	return &Generator{
+		rng:     rand.New(rand.NewSource(g.Seed + int64(worker))),
+		schema:  g.Schema,
+		devices: map[int]*deviceState{},
+	}
+}
+
+// Generator 按时间戳顺序生成一个客户端的测试数据, 非并发安全
+type Generator struct {
+	rng     *rand.Rand
+	schema  *Schema
+	devices map[int]*deviceState
+}
+
+// deviceState 每个设备独立的取值模型状态和标签值
+type deviceState struct {
+	models []ValueModel
+	tags   []interface{}
+}
+
+func (g *Generator) device(id int) *deviceState {
+	d, ok := g.devices[id]
+	if !ok {
+		d = &deviceState{}
+		for _, c := range g.schema.Columns {
+			d.models = append(d.models, c.Model.New())
+		}
+		if g.schema.Devices > 0 {
+			d.tags = g.schema.TagValues(id)
+		}
+		g.devices[id] = d
+	}
+	return d
+}
+
+// Next 生成设备 device 时间戳为 ts 的一条记录, 可为空的列按 NullRate 生成 NULL。
+// 未按设备写入(Schema.Devices 为 0)时 device 传 0
+func (g *Generator) Next(ts int64, device int) Record {
+	d := g.device(device)
+	rec := Record{Ts: ts, Device: device, Values: make([]interface{}, len(g.schema.Columns)), Tags: d.tags}
+	for i, c := range g.schema.Columns {
+		// 无论是否为 NULL 都先取值, 保证相同种子生成的非空值不受 NullRate 影响
+		v := d.models[i].Next(g.rng)
+		if c.Nullable && g.rng.Float64() < c.NullRate {
+			continue
+		}
+		rec.Values[i] = c.Convert(v)
+	}
+	return rec
+}
+
+// TagValues 返回第 table 张表(按设备写入时为第 table 个设备)的标签列取值, 只与序号有关, 各数据库相同
+func (s *Schema) TagValues(table int) []interface{} {
+	if len(s.Tags) == 0 {
+		return nil
+	}
+	rng := rand.New(rand.NewSource(int64(table)))
+	values := make([]interface{}, len(s.Tags))
+	for i, c := range s.Tags {
+		values[i] = c.Convert(c.Model.New().Next(rng))
+	}
+	return values
+}
+
+// TableTags 根据写入模式返回第 worker 个客户端写入的表的标签列取值
+func (s *Schema) TableTags(mode string, worker int) []interface{} {
+	if mode == Multi {
+		return s.TagValues(worker)
+	}
+	return s.TagValues(0)
+}
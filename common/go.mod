@@ -1,7 +0,0 @@
-module performance_testing/common
-
-go 1.20
-
-require github.com/astaxie/beego v1.12.3
-
-require github.com/shiena/ansicolor v0.0.0-20151119151921-a422bbe96644 // indirect
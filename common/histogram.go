@@ -0,0 +1,218 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// 每个 2 的幂次区间划分的子桶数, 相对误差不超过 1/128
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+)
+
+// Histogram HDR 风格的延迟直方图, 以微秒为单位按对数-线性分桶记录, 内存占用固定, 可以合并。
+// 不是并发安全的, 每个协程使用自己的直方图, 结束后合并
+type Histogram struct {
+	counts []int64
+	count  int64
+	sum    int64
+	sumSq  float64 // 平方和, 用于计算标准差
+	min    int64
+	max    int64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]int64, (64-subBucketBits+1)*subBucketCount), min: math.MaxInt64}
+}
+
+// bucketIndex 小于 subBucketCount 的值每个值一个桶, 更大的值按最高的 subBucketBits+1 位分桶
+func bucketIndex(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits - 1
+	return (shift+1)*subBucketCount + int(v>>shift) - subBucketCount
+}
+
+// bucketUpper 第 i 个桶中的最大值
+func bucketUpper(i int) int64 {
+	if i < subBucketCount {
+		return int64(i)
+	}
+	shift := i/subBucketCount - 1
+	return (int64(i%subBucketCount+subBucketCount)+1)<<shift - 1
+}
+
+// Record 记录一次耗时
+func (h *Histogram) Record(d time.Duration) {
+	v := d.Microseconds()
+	if v < 0 {
+		v = 0
+	}
+	h.counts[bucketIndex(v)]++
+	h.count++
+	h.sum += v
+	h.sumSq += float64(v) * float64(v)
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// Merge 将 o 中的记录合并到 h
+func (h *Histogram) Merge(o *Histogram) {
+	if o == nil || o.count == 0 {
+		return
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.count += o.count
+	h.sum += o.sum
+	h.sumSq += o.sumSq
+	if o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+func (h *Histogram) Count() int64 {
+	return h.count
+}
+
+func (h *Histogram) Min() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.min) * time.Microsecond
+}
+
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max) * time.Microsecond
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum/h.count) * time.Microsecond
+}
+
+// Percentile 返回第 q 百分位(0-100)的耗时, 为所在桶的最大值, 不超过记录的最大值
+func (h *Histogram) Percentile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var n int64
+	for i, c := range h.counts {
+		n += c
+		if n >= rank {
+			v := bucketUpper(i)
+			if v > h.max {
+				v = h.max
+			}
+			return time.Duration(v) * time.Microsecond
+		}
+	}
+	return h.Max()
+}
+
+// Summary 返回直方图的统计值, 百分位为所在桶的最大值
+func (h *Histogram) Summary() LatencySummary {
+	s := LatencySummary{
+		Count:  int(h.count),
+		Min:    h.Min(),
+		Mean:   h.Mean(),
+		Median: h.Percentile(50),
+		P90:    h.Percentile(90),
+		P95:    h.Percentile(95),
+		P99:    h.Percentile(99),
+		P999:   h.Percentile(99.9),
+		Max:    h.Max(),
+	}
+	if h.count > 0 {
+		mean := float64(h.sum) / float64(h.count)
+		s.Stddev = time.Duration(math.Sqrt(math.Max(0, h.sumSq/float64(h.count)-mean*mean))) * time.Microsecond
+	}
+	return s
+}
+
+// String 如 count=100 p50=1.234ms p90=... p99=... p99.9=... max=...
+func (h *Histogram) String() string {
+	return fmt.Sprintf("count=%d p50=%s p90=%s p99=%s p99.9=%s max=%s", h.count,
+		formatMs(h.Percentile(50)), formatMs(h.Percentile(90)), formatMs(h.Percentile(99)), formatMs(h.Percentile(99.9)), formatMs(h.Max()))
+}
+
+func formatMs(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d.Microseconds())/1000)
+}
+
+// LatencySummary 一组耗时样本的统计值
+type LatencySummary struct {
+	Count  int
+	Min    time.Duration
+	Mean   time.Duration
+	Median time.Duration
+	P90    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+	P999   time.Duration
+	Max    time.Duration
+	Stddev time.Duration
+}
+
+// Summarize 计算样本的统计值, 百分位取最近秩
+func Summarize(samples []time.Duration) LatencySummary {
+	s := LatencySummary{Count: len(samples)}
+	if len(samples) == 0 {
+		return s
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(q float64) time.Duration {
+		i := int(math.Ceil(q/100*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return sorted[i]
+	}
+
+	var sum float64
+	for _, d := range sorted {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(sorted))
+	var variance float64
+	for _, d := range sorted {
+		variance += (float64(d) - mean) * (float64(d) - mean)
+	}
+	variance /= float64(len(sorted))
+
+	s.Min = sorted[0]
+	s.Max = sorted[len(sorted)-1]
+	s.Mean = time.Duration(mean)
+	s.Median = rank(50)
+	s.P90 = rank(90)
+	s.P95 = rank(95)
+	s.P99 = rank(99)
+	s.P999 = rank(99.9)
+	s.Stddev = time.Duration(math.Sqrt(variance))
+	return s
+}
+
+func (s LatencySummary) String() string {
+	return fmt.Sprintf("n=%d min=%s mean=%s median=%s p95=%s p99=%s max=%s stddev=%s", s.Count,
+		formatMs(s.Min), formatMs(s.Mean), formatMs(s.Median), formatMs(s.P95), formatMs(s.P99), formatMs(s.Max), formatMs(s.Stddev))
+}
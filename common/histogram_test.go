@@ -0,0 +1,157 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketIndex(t *testing.T) {
+	tests := []struct {
+		v     int64
+		index int
+		upper int64
+	}{
+		{0, 0, 0},
+		{1, 1, 1},
+		{127, 127, 127},
+		{128, 128, 128},
+		{255, 255, 255},
+		{256, 256, 257},
+		{257, 256, 257},
+		{258, 257, 259},
+		{1000, 506, 1003},
+		{1003, 506, 1003},
+		{1004, 507, 1007},
+	}
+	for _, tt := range tests {
+		index := bucketIndex(tt.v)
+		if index != tt.index {
+			t.Errorf("bucketIndex(%d) = %d, want %d", tt.v, index, tt.index)
+		}
+		if upper := bucketUpper(index); upper != tt.upper {
+			t.Errorf("bucketUpper(%d) = %d, want %d", index, upper, tt.upper)
+		}
+	}
+}
+
+// 每个值落在上界不小于它、前一个桶上界小于它的桶中, 相对误差不超过 1/subBucketCount
+func TestBucketBounds(t *testing.T) {
+	for v := int64(1); v < 1<<40; v = v*3/2 + 1 {
+		for _, x := range []int64{v - 1, v, v + 1} {
+			i := bucketIndex(x)
+			upper := bucketUpper(i)
+			if upper < x {
+				t.Fatalf("bucketUpper(bucketIndex(%d)) = %d, less than the value", x, upper)
+			}
+			if i > 0 && bucketUpper(i-1) >= x {
+				t.Fatalf("bucketUpper(%d) = %d, value %d belongs to the previous bucket", i-1, bucketUpper(i-1), x)
+			}
+			if float64(upper-x) > float64(x)/subBucketCount {
+				t.Fatalf("bucket of %d has upper bound %d, relative error exceeds 1/%d", x, upper, subBucketCount)
+			}
+		}
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, 1 * time.Microsecond},
+		{1, 1 * time.Microsecond},
+		{50, 50 * time.Microsecond},
+		{90, 90 * time.Microsecond},
+		{99, 99 * time.Microsecond},
+		{99.9, 100 * time.Microsecond},
+		{100, 100 * time.Microsecond},
+	}
+	for _, tt := range tests {
+		if got := h.Percentile(tt.q); got != tt.want {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if h.Count() != 100 || h.Min() != time.Microsecond || h.Max() != 100*time.Microsecond || h.Mean() != 50*time.Microsecond {
+		t.Errorf("count=%d min=%v max=%v mean=%v, want 100, 1µs, 100µs, 50µs", h.Count(), h.Min(), h.Max(), h.Mean())
+	}
+}
+
+// 百分位取所在桶的上界, 但不超过记录的最大值
+func TestHistogramPercentileCappedAtMax(t *testing.T) {
+	h := NewHistogram()
+	h.Record(time.Millisecond)
+	if got := h.Percentile(99); got != time.Millisecond {
+		t.Errorf("Percentile(99) = %v, want 1ms", got)
+	}
+	h.Record(2 * time.Millisecond)
+	// 1000µs 所在桶的上界为 1003µs
+	if got := h.Percentile(50); got != 1003*time.Microsecond {
+		t.Errorf("Percentile(50) = %v, want 1.003ms", got)
+	}
+}
+
+func TestHistogramEmpty(t *testing.T) {
+	h := NewHistogram()
+	if h.Percentile(99) != 0 || h.Min() != 0 || h.Max() != 0 || h.Mean() != 0 {
+		t.Errorf("empty histogram: p99=%v min=%v max=%v mean=%v, want 0", h.Percentile(99), h.Min(), h.Max(), h.Mean())
+	}
+	// 负的耗时按 0 记录
+	h.Record(-time.Second)
+	if h.Count() != 1 || h.Max() != 0 {
+		t.Errorf("negative duration: count=%d max=%v, want 1, 0", h.Count(), h.Max())
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	all, low, high := NewHistogram(), NewHistogram(), NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		d := time.Duration(i*i) * time.Microsecond
+		all.Record(d)
+		if i <= 500 {
+			low.Record(d)
+		} else {
+			high.Record(d)
+		}
+	}
+	merged := NewHistogram()
+	merged.Merge(low)
+	merged.Merge(nil)
+	merged.Merge(NewHistogram())
+	merged.Merge(high)
+	if merged.Summary() != all.Summary() {
+		t.Errorf("merged summary %v, want %v", merged.Summary(), all.Summary())
+	}
+	for _, q := range []float64{50, 90, 99, 99.9} {
+		if merged.Percentile(q) != all.Percentile(q) {
+			t.Errorf("merged Percentile(%v) = %v, want %v", q, merged.Percentile(q), all.Percentile(q))
+		}
+	}
+	// 合并不修改 o
+	if low.Count() != 500 || low.Max() != 250000*time.Microsecond {
+		t.Errorf("merge modified its argument: count=%d max=%v", low.Count(), low.Max())
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	s := Summarize(samples)
+	want := LatencySummary{Count: 100, Min: time.Millisecond, Mean: 50500 * time.Microsecond, Median: 50 * time.Millisecond,
+		P90: 90 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond, P999: 100 * time.Millisecond, Max: 100 * time.Millisecond}
+	s.Stddev = 0
+	if s != want {
+		t.Errorf("Summarize = %+v, want %+v", s, want)
+	}
+	if samples[0] != 100*time.Millisecond {
+		t.Errorf("Summarize sorted its argument")
+	}
+	if s := Summarize(nil); s.Count != 0 || s.Max != 0 {
+		t.Errorf("Summarize(nil) = %+v, want zero", s)
+	}
+}
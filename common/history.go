@@ -0,0 +1,126 @@
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const HistoryFile = "history.jsonl"
+
+// AppendHistory 将结果追加到 dir 目录下的历史记录 history.jsonl, 每行一个 json, 并生成结果的 Id
+func AppendHistory(dir string, r *Result) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("create history dir %s fail, err:%v\n", dir, err)
+		return err
+	}
+	r.Id = strings.ToLower(r.Target) + "-" + r.Command + "-" + r.StartTime.Format("20060102-150405.000")
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		fmt.Printf("encode result fail, err:%v\n", err)
+		return err
+	}
+	path := filepath.Join(dir, HistoryFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Printf("open history file %s fail, err:%v\n", path, err)
+		return err
+	}
+	defer f.Close()
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		fmt.Printf("write history file %s fail, err:%v\n", path, err)
+		return err
+	}
+	fmt.Printf("result has saved to history %s, id:%s\n", path, r.Id)
+	return nil
+}
+
+// LoadHistory 按保存顺序读取 dir 目录下的全部历史结果
+func LoadHistory(dir string) (error, []*Result) {
+	path := filepath.Join(dir, HistoryFile)
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("open history file %s fail, err:%v\n", path, err)
+		return err, nil
+	}
+	defer f.Close()
+
+	var results []*Result
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		r := &Result{}
+		if err = json.Unmarshal(scanner.Bytes(), r); err != nil {
+			err = errors.New(fmt.Sprintf("parse history file %s line %d fail, err:%v", path, line, err))
+			fmt.Printf("%v\n", err)
+			return err, nil
+		}
+		results = append(results, r)
+	}
+	return scanner.Err(), results
+}
+
+// LoadResult 读取 WriteJSON 输出的结果文件
+func LoadResult(path string) (error, *Result) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("read result file %s fail, err:%v\n", path, err)
+		return err, nil
+	}
+	r := &Result{}
+	if err = json.Unmarshal(data, r); err != nil {
+		fmt.Printf("parse result file %s fail, err:%v\n", path, err)
+		return err, nil
+	}
+	return nil, r
+}
+
+// FindRun 在历史结果中查找 ref 指定的一次测试: latest 为最近一次, previous 为倒数第二次, 否则先按 Id 查找, 再按 Label 查找最近一次。
+// like 不为 nil 时只在 like 之前保存的、数据库和子命令与 like 相同的结果中查找, 此时 latest 与 previous 均为 like 的前一次
+func FindRun(results []*Result, ref string, like *Result) (error, *Result) {
+	var candidates []*Result
+	for _, r := range results {
+		if like != nil {
+			if like.Id != "" && r.Id == like.Id {
+				break
+			}
+			if r.Target != like.Target || r.Command != like.Command {
+				continue
+			}
+		}
+		candidates = append(candidates, r)
+	}
+
+	switch ref {
+	case "latest", "previous":
+		i := len(candidates) - 1
+		if ref == "previous" && like == nil {
+			i--
+		}
+		if i >= 0 {
+			return nil, candidates[i]
+		}
+	default:
+		for _, r := range candidates {
+			if r.Id == ref {
+				return nil, r
+			}
+		}
+		for i := len(candidates) - 1; i >= 0; i-- {
+			if candidates[i].Label == ref {
+				return nil, candidates[i]
+			}
+		}
+	}
+	err := errors.New(fmt.Sprintf("run '%s' not found in history", ref))
+	fmt.Printf("%v\n", err)
+	return err, nil
+}
@@ -0,0 +1,344 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	client "github.com/influxdata/influxdb1-client/v2"
+)
+
+// InfluxBackend InfluxDB 1.x 写入与查询, 表对应 measurement
+type InfluxBackend struct {
+	conf   *DBConfig
+	mode   string
+	schema *Schema
+	dbList []client.Client
+}
+
+func NewInfluxBackend(conf *DBConfig, mode string, schema *Schema) *InfluxBackend {
+	return &InfluxBackend{
+		conf:   conf,
+		mode:   mode,
+		schema: schema,
+	}
+}
+
+func (f *InfluxBackend) Name() string {
+	return InfluxDB
+}
+
+func (f *InfluxBackend) Version() string {
+	_, version, err := f.dbList[0].Ping(5 * time.Second)
+	if err != nil {
+		fmt.Printf("query version fail:%v\n", err)
+	}
+	return version
+}
+
+// Retryable 写入超时、缓存已满、服务端 5xx 和网络错误可以重试, 字段类型冲突等 4xx 错误不可重试
+func (f *InfluxBackend) Retryable(err error) bool {
+	return IsTransientError(err) || errorContains(err, "timeout", "cache-max-memory-size exceeded", "engine: cache maximum memory size exceeded",
+		"service unavailable", "bad gateway", "internal server error", "hinted handoff queue not empty")
+}
+
+// SkipAllNull 没有 field 的 point 不能写入, 全部列为 NULL 的记录跳过
+func (f *InfluxBackend) SkipAllNull() bool {
+	return true
+}
+
+func (f *InfluxBackend) CheckArgs(p WriteParams) error {
+	return CheckMode(f.mode)
+}
+
+func (f *InfluxBackend) Connect(T1 int) error {
+	fmt.Printf("start create db conn, count:%d\n", T1)
+	addr := fmt.Sprintf("http://%s:%s", f.conf.Host, f.conf.Port)
+	for i := 0; i < T1; i++ {
+		c, err := client.NewHTTPClient(client.HTTPConfig{
+			Addr:     addr,
+			Username: f.conf.User,
+			Password: f.conf.Password,
+		})
+
+		if err != nil {
+			fmt.Printf("database clinet[%d] failed:%v\n", i+1, err) //Connection failed
+			return err
+		} else {
+			fmt.Printf("database clinet[%d] created.\n", i+1) //Connection succeed
+			f.dbList = append(f.dbList, c)
+		}
+	}
+	return nil
+}
+
+// query 执行一条 InfluxQL 并检查响应中的错误
+func (f *InfluxBackend) query(worker int, q string) (error, *client.Response) {
+	query := client.NewQuery(q, Database, "ns")
+	response, err := f.dbList[worker].Query(query)
+	if err != nil {
+		fmt.Println(err)
+		return err, response
+	}
+
+	// 处理响应
+	if err = response.Error(); err != nil {
+		fmt.Println(err)
+		return err, response
+	}
+	return nil, response
+}
+
+// InitTable 导入数据前先删除test数据库，再创建数据库，后续向test库中写数据d0、d1……
+func (f *InfluxBackend) InitTable(T1 int) error {
+	if err, _ := f.query(0, `DROP DATABASE `+Database); err != nil {
+		fmt.Printf(" drop database %s failed, err:%v\n", Database, err)
+		return err
+	}
+
+	if err, _ := f.query(0, `CREATE DATABASE `+Database); err != nil {
+		fmt.Printf(" create database %s failed, err:%v\n", Database, err)
+		return err
+	}
+
+	fmt.Printf("Initialize database completed.\n")
+	return nil
+}
+
+func (f *InfluxBackend) TruncateTables(T1 int) error {
+	tableCount := 1
+	if f.mode == Multi && f.schema.Devices == 0 {
+		tableCount = T1
+	}
+	for z := 0; z < tableCount; z++ {
+		// 执行删除表的操作 DROP measurement d0;
+		tableName := TableName(f.mode, f.conf.TablePrefix, z)
+		if err, _ := f.query(0, `DROP measurement `+tableName); err != nil {
+			fmt.Printf(" drop table %s failed, err:%v\n", tableName, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// measurement 第 worker 个客户端写入的 measurement, 按设备写入时所有设备写同一个 measurement
+func (f *InfluxBackend) measurement(worker int) string {
+	if f.schema.Devices > 0 {
+		return Table
+	}
+	return TableName(f.mode, f.conf.TablePrefix, worker)
+}
+
+func (f *InfluxBackend) RecordTable(worker int, rec Record) string {
+	return f.measurement(worker)
+}
+
+// QueryChecksum count(*) 按 field 分别计数, 可为空的 field 不写入, 取各 field 计数的最大值作为记录数; 不支持对 time 求和
+func (f *InfluxBackend) QueryChecksum(table string, sums bool) (error, TableChecksum) {
+	checksum := TableChecksum{Sums: map[string]float64{}}
+	exprs := []string{"count(*)"}
+	if sums {
+		for _, c := range f.schema.Columns {
+			if c.IsNumeric() {
+				exprs = append(exprs, fmt.Sprintf(`sum("%s") as "%s"`, c.Name, c.Name))
+			}
+		}
+	}
+	err, response := f.query(0, fmt.Sprintf(`select %s from "%s"`, strings.Join(exprs, ", "), table))
+	if err != nil {
+		return err, checksum
+	}
+	for _, r := range response.Results {
+		for _, series := range r.Series {
+			if len(series.Values) == 0 {
+				continue
+			}
+			// 第一列为 time
+			for i, col := range series.Columns[1:] {
+				v, ok := series.Values[0][i+1].(json.Number)
+				if !ok {
+					continue
+				}
+				value, err := v.Float64()
+				if err != nil {
+					return err, checksum
+				}
+				if strings.HasPrefix(col, "count_") {
+					checksum.Rows = max(checksum.Rows, int64(value))
+				} else {
+					checksum.Sums[col] = value
+				}
+			}
+		}
+	}
+	// 没有写入任何记录时结果为空, 数值列之和按 0 比较
+	for _, c := range f.schema.Columns {
+		if _, ok := checksum.Sums[c.Name]; sums && c.IsNumeric() && !ok {
+			checksum.Sums[c.Name] = 0
+		}
+	}
+	return nil, checksum
+}
+
+// Queried 查询测试读取 measurement d0
+func (f *InfluxBackend) Queried(table string) bool {
+	return table == Table
+}
+
+func (f *InfluxBackend) EncodeBatch(worker int, records []Record) (error, interface{}) {
+	// 按设备写入时以 device_id 及设备的标签值作为 tag 区分时间线
+	tableName := f.measurement(worker)
+	bp, err := client.NewBatchPoints(client.BatchPointsConfig{
+		Database: Database,
+		//Precision: "s",
+	})
+	if err != nil {
+		return err, nil
+	}
+
+	// 标签列写为 tag, 数据列写为 field, NULL 值的 field 不写, 没有 field 的记录跳过
+	tags := f.tags(f.schema.TableTags(f.mode, worker))
+	for _, rec := range records {
+		if f.schema.Devices > 0 {
+			tags = f.tags(rec.Tags)
+			tags[DeviceColumn] = strconv.Itoa(rec.Device)
+		}
+		fields := make(map[string]interface{}, len(rec.Values))
+		for i, v := range rec.Values {
+			if v != nil {
+				fields[f.schema.Columns[i].Name] = v
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		pt, err := client.NewPoint(tableName, tags, fields, time.UnixMilli(rec.Ts))
+		if err != nil {
+			return err, nil
+		}
+		bp.AddPoint(pt)
+	}
+	return nil, bp
+}
+
+func (f *InfluxBackend) tags(values []interface{}) map[string]string {
+	tags := make(map[string]string, len(values)+1)
+	for i, v := range values {
+		tags[f.schema.Tags[i].Name] = FormatValue(v, "")
+	}
+	return tags
+}
+
+func (f *InfluxBackend) WriteBatch(worker int, batch interface{}) error {
+	return f.dbList[worker].Write(batch.(client.BatchPoints))
+}
+
+func (f *InfluxBackend) Flush(worker int) error {
+	return nil
+}
+
+func (f *InfluxBackend) Queries() []string {
+	return []string{QueryNameCount, QueryNameSelect, QueryNamePoint, QueryNameAvg, QueryNameSum, QueryNameMax, QueryNameMin, QueryNameTimeWindow, QueryNameLatest}
+}
+
+func (f *InfluxBackend) RunQuery(worker int, name string) (error, float64) {
+	var q string
+	column := f.schema.AggColumn()
+	if column == "" && name != QueryNameCount && name != QueryNameSelect && name != QueryNamePoint {
+		return errors.New(fmt.Sprintf("unsupported query:%s, schema has no numeric column", name)), 0
+	}
+	switch name {
+	case QueryNameCount:
+		q = fmt.Sprintf("select count(*) from %s", Table)
+	case QueryNameSelect:
+		q = fmt.Sprintf("select * from %s", Table)
+	case QueryNamePoint:
+		q = fmt.Sprintf("select * from %s where time=%s", Table, f.conf.PointQueryTsCondition)
+	case QueryNameAvg:
+		q = fmt.Sprintf("select MEAN(%s) from %s", column, Table)
+	case QueryNameSum:
+		q = fmt.Sprintf("select sum(%s) from %s", column, Table)
+	case QueryNameMax:
+		q = fmt.Sprintf("select max(%s) from %s", column, Table)
+	case QueryNameMin:
+		q = fmt.Sprintf("select min(%s) from %s", column, Table)
+	case QueryNameLatest:
+		q = fmt.Sprintf("select last(%s) from %s", column, Table)
+	case QueryNameTimeWindow:
+		q = fmt.Sprintf("select max(%s), min(%s) from %s where time >= %s and time < %s group by time(60m)", column, column, Table, f.conf.InfluxdbTimeWindowStart, f.conf.InfluxdbTimeWindowEnd)
+		fmt.Printf("TimeWindow query sql:%s\n", q)
+	default:
+		return errors.New(fmt.Sprintf("unsupported query:%s", name)), 0
+	}
+
+	err, result := f.query(worker, q)
+	if err != nil {
+		return err, 0
+	}
+
+	// 遍历结果: count、聚合查询取第一行的值, 其余查询返回行数
+	var rowCount int
+	for _, r := range result.Results {
+		for _, series := range r.Series {
+			for _, values := range series.Values {
+				switch name {
+				case QueryNameCount:
+					// count(*) 按 field 分别计数, 可为空的 field 不写入, 取各 field 计数的最大值作为记录数
+					var count float64
+					for _, v := range values[1:] {
+						if v == nil {
+							continue
+						}
+						err, value := influxNumber(name, v)
+						if err != nil {
+							return err, 0
+						}
+						count = max(count, value)
+					}
+					fmt.Printf(" %s value is:%v\n", name, count)
+					return nil, count
+				case QueryNameAvg, QueryNameSum, QueryNameMax, QueryNameMin, QueryNameLatest:
+					err, value := influxNumber(name, values[1])
+					if err != nil {
+						return err, 0
+					}
+					fmt.Printf(" %s value is:%v\n", name, value)
+					return nil, value
+				case QueryNamePoint:
+					fmt.Printf(" point query result: %v\n", values)
+				}
+				rowCount++
+			}
+		}
+	}
+	return nil, float64(rowCount)
+}
+
+// influxNumber 查询结果中的数值, 为 null(如全部为 NULL 的列的 last、max)时返回错误
+func influxNumber(name string, v interface{}) (error, float64) {
+	n, ok := v.(json.Number)
+	if !ok {
+		if v == nil {
+			v = "null"
+		}
+		err := errors.New(fmt.Sprintf("%s query returned %v, not a number", name, v))
+		fmt.Printf("%v\n", err)
+		return err, 0
+	}
+	value, err := n.Float64()
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return err, 0
+	}
+	return nil, value
+}
+
+func (f *InfluxBackend) Close() {
+	for _, conn := range f.dbList {
+		conn.Close()
+	}
+}
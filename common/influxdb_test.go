@@ -0,0 +1,45 @@
+package common
+
+import (
+	"testing"
+
+	client "github.com/influxdata/influxdb1-client/v2"
+)
+
+func TestInfluxEncodeBatchSkipsAllNull(t *testing.T) {
+	schema := &Schema{Columns: []Column{
+		{Name: "current", Type: TypeFloat, Nullable: true},
+		{Name: "voltage", Type: TypeInt, Nullable: true},
+	}}
+	if err := schema.init(); err != nil {
+		t.Fatal(err)
+	}
+	f := NewInfluxBackend(&DBConfig{}, Multi, schema)
+	records := []Record{
+		{Ts: StartTimestamp, Values: []interface{}{nil, nil}},
+		{Ts: StartTimestamp + 1, Values: []interface{}{1.5, nil}},
+		{Ts: StartTimestamp + 2, Values: []interface{}{nil, nil}},
+	}
+	if !f.SkipAllNull() || !records[0].AllNull() || records[1].AllNull() {
+		t.Fatalf("SkipAllNull=%v, AllNull=%v,%v, want true, true, false", f.SkipAllNull(), records[0].AllNull(), records[1].AllNull())
+	}
+
+	err, batch := f.EncodeBatch(0, records)
+	if err != nil {
+		t.Fatalf("EncodeBatch with all-NULL records: %v", err)
+	}
+	points := batch.(client.BatchPoints).Points()
+	if len(points) != 1 {
+		t.Fatalf("EncodeBatch encoded %d points, want 1", len(points))
+	}
+	fields, err := points[0].Fields()
+	if err != nil || len(fields) != 1 || fields["current"] != 1.5 || points[0].Time().UnixMilli() != StartTimestamp+1 {
+		t.Errorf("point %v, fields %v, %v, want current=1.5 at the second timestamp", points[0], fields, err)
+	}
+
+	// 全部记录都为 NULL 时为空请求
+	err, batch = f.EncodeBatch(0, records[:1])
+	if err != nil || len(batch.(client.BatchPoints).Points()) != 0 {
+		t.Errorf("EncodeBatch of an all-NULL record = %v, %v, want no points", batch, err)
+	}
+}
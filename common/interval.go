@@ -0,0 +1,101 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultInterval 指定 Duration 未指定 Interval 时的区间长度
+const DefaultInterval = 10 * time.Second
+
+// IntervalResult 一个区间的写入结果, 用于观察长时间写入时 compaction、merge、内存增长等引起的性能变化
+type IntervalResult struct {
+	Time       time.Time      `json:"time"`    // 区间结束时间
+	Elapsed    float64        `json:"elapsed"` // 区间结束时距本轮开始的秒数
+	Seconds    float64        `json:"seconds"`
+	Records    int            `json:"records"`
+	Throughput float64        `json:"throughput"` // records/second
+	Latency    *LatencyResult `json:"latency"`
+}
+
+// intervalRecorder 汇总所有写入协程在当前区间的记录数与请求耗时, 每隔 interval 输出一行并开始新的区间
+type intervalRecorder struct {
+	mu        sync.Mutex
+	start     time.Time
+	last      time.Time
+	rows      int
+	latency   *Histogram
+	intervals []IntervalResult
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// newIntervalRecorder interval 为 0 时返回 nil, 不记录区间
+func newIntervalRecorder(interval time.Duration) *intervalRecorder {
+	if interval <= 0 {
+		return nil
+	}
+	now := time.Now()
+	r := &intervalRecorder{start: now, last: now, latency: NewHistogram(), stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				r.flush(now)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// record 记录一次写入请求, r 为 nil 时忽略
+func (r *intervalRecorder) record(rows int, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.rows += rows
+	r.latency.Record(d)
+	r.mu.Unlock()
+}
+
+// flush 结束当前区间并输出
+func (r *intervalRecorder) flush(now time.Time) {
+	r.mu.Lock()
+	rows, latency, start := r.rows, r.latency, r.last
+	r.rows, r.latency, r.last = 0, NewHistogram(), now
+	r.mu.Unlock()
+
+	seconds := now.Sub(start).Seconds()
+	if seconds <= 0 {
+		return
+	}
+	ir := IntervalResult{
+		Time:       now,
+		Elapsed:    now.Sub(r.start).Seconds(),
+		Seconds:    seconds,
+		Records:    rows,
+		Throughput: float64(rows) / seconds,
+		Latency:    NewLatencyResult(latency.Summary()),
+	}
+	r.intervals = append(r.intervals, ir)
+	fmt.Printf("[%8s] %d records, %f records/second, batch p50 %.3fms p99 %.3fms max %.3fms\n",
+		now.Sub(r.start).Round(100*time.Millisecond), rows, ir.Throughput, ir.Latency.P50, ir.Latency.P99, ir.Latency.Max)
+}
+
+// finish 停止定时输出, 输出最后一个不完整的区间, 返回全部区间
+func (r *intervalRecorder) finish() []IntervalResult {
+	if r == nil {
+		return nil
+	}
+	close(r.stop)
+	<-r.done
+	r.flush(time.Now())
+	return r.intervals
+}
@@ -0,0 +1,268 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMix 混合读写默认的查询组合, 各项比例相同, 不支持的查询项忽略
+var DefaultMix = []string{QueryNamePoint, QueryNameAvg, QueryNameTimeWindow, QueryNameLatest}
+
+// MixedParams 混合读写测试中查询端的参数
+type MixedParams struct {
+	Readers int            // 查询客户端(协程)数
+	Mix     map[string]int // 查询项及其比例, 如 point=4,avg=1; nil 表示 DefaultMix
+	QPS     float64        // 所有查询客户端合计的目标 QPS, 0 表示不限制
+}
+
+// ParseMix 解析查询组合, 如 point=4,avg=1,timeWindow=1,latest=4, 省略比例时为 1
+func ParseMix(spec string) (error, map[string]int) {
+	mix := map[string]int{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, weight, found := strings.Cut(item, "=")
+		w := 1
+		if found {
+			var err error
+			if w, err = strconv.Atoi(strings.TrimSpace(weight)); err != nil || w < 0 {
+				err = errors.New(fmt.Sprintf("invalid mix '%s', required to be query=weight", item))
+				fmt.Printf("%v\n", err)
+				return err, nil
+			}
+		}
+		if w > 0 {
+			mix[strings.TrimSpace(name)] = w
+		}
+	}
+	if len(mix) == 0 {
+		err := errors.New(fmt.Sprintf("invalid mix '%s', no queries", spec))
+		fmt.Printf("%v\n", err)
+		return err, nil
+	}
+	return nil, mix
+}
+
+// queryMix 按比例随机选择查询项
+type queryMix struct {
+	names   []string
+	weights []int
+	total   int
+}
+
+// newQueryMix 校验 b 是否支持组合中的查询项, 按名称排序以便相同的种子得到相同的查询序列
+func newQueryMix(b Backend, mix map[string]int) (error, *queryMix) {
+	supported := map[string]bool{}
+	for _, name := range b.Queries() {
+		supported[name] = true
+	}
+	if mix == nil {
+		mix = map[string]int{}
+		for _, name := range DefaultMix {
+			if supported[name] {
+				mix[name] = 1
+			}
+		}
+	}
+
+	m := &queryMix{}
+	for name := range mix {
+		if !supported[name] {
+			err := errors.New(fmt.Sprintf("%s does not support query:%s, supported queries are %s", b.Name(), name, strings.Join(b.Queries(), "|")))
+			fmt.Printf("%v\n", err)
+			return err, nil
+		}
+		m.names = append(m.names, name)
+	}
+	sort.Strings(m.names)
+	for _, name := range m.names {
+		m.weights = append(m.weights, mix[name])
+		m.total += mix[name]
+	}
+	return nil, m
+}
+
+func (m *queryMix) pick(rng *rand.Rand) string {
+	n := rng.Intn(m.total)
+	for i, w := range m.weights {
+		if n < w {
+			return m.names[i]
+		}
+		n -= w
+	}
+	return m.names[len(m.names)-1]
+}
+
+func (m *queryMix) String() string {
+	var items []string
+	for i, name := range m.names {
+		items = append(items, fmt.Sprintf("%s=%d", name, m.weights[i]))
+	}
+	return strings.Join(items, ",")
+}
+
+// readStats 一个查询项在全部查询客户端和轮次中的耗时与失败次数
+type readStats struct {
+	latency *Histogram
+	errors  int
+}
+
+// runReaders 启动 Readers 个查询客户端, 使用第 T 个及之后的连接按比例执行查询, 直到 stop 关闭; 查询失败后退避一段时间再继续。
+// 返回的函数等待全部客户端退出, 并将各查询项的结果合并到 stats
+func runReaders(b Backend, p WriteParams, m MixedParams, mix *queryMix, stop <-chan struct{}, stats map[string]*readStats) func() {
+	pace := newPacer(m.QPS)
+	results := make([]map[string]*readStats, m.Readers)
+	var wg sync.WaitGroup
+	for j := 0; j < m.Readers; j++ {
+		results[j] = map[string]*readStats{}
+		wg.Add(1)
+		go func(reader int) {
+			defer wg.Done()
+			worker := p.T + reader
+			rng := rand.New(rand.NewSource(p.Gen.Seed + int64(worker)))
+			// 连续失败的次数, 查询失败后按 DefaultRetryPolicy 退避, 以免不限 QPS 时失败的查询反复执行
+			var failures int
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				pace.wait()
+				name := mix.pick(rng)
+				rs := results[reader][name]
+				if rs == nil {
+					rs = &readStats{latency: NewHistogram()}
+					results[reader][name] = rs
+				}
+				queryStart := time.Now()
+				if err, _ := b.RunQuery(worker, name); err != nil {
+					rs.errors++
+					failures++
+					select {
+					case <-stop:
+						return
+					case <-time.After(DefaultRetryPolicy.Delay(failures, rng)):
+					}
+					continue
+				}
+				failures = 0
+				rs.latency.Record(time.Since(queryStart))
+			}
+		}(j)
+	}
+
+	return func() {
+		wg.Wait()
+		for _, result := range results {
+			for name, rs := range result {
+				if stats[name] == nil {
+					stats[name] = &readStats{latency: NewHistogram()}
+				}
+				stats[name].latency.Merge(rs.latency)
+				stats[name].errors += rs.errors
+			}
+		}
+	}
+}
+
+// RunMixed 执行混合读写测试: 每轮写入的同时由 Readers 个客户端按比例执行查询, 写入结束时查询也结束。
+// 写入指标同 RunWrite, 每个查询项的耗时分布、实际 QPS 与失败次数记录在结果的 Queries 中
+func RunMixed(b Backend, p WriteParams, m MixedParams) (error, *Result) {
+	result := newResult("mixed", b)
+	if m.Readers <= 0 || m.QPS < 0 {
+		err := errors.New(fmt.Sprintf("invalid mixed args: readers=%d, qps=%f", m.Readers, m.QPS))
+		fmt.Printf("%v\n", err)
+		return result.finish(err)
+	}
+	if err := checkQueryArgs(b); err != nil {
+		return result.finish(err)
+	}
+	err, mix := newQueryMix(b, m.Mix)
+	if err != nil {
+		return result.finish(err)
+	}
+	if err = prepareWrite(b, &p, p.T+m.Readers); err != nil {
+		return result.finish(err)
+	}
+	defer b.Close()
+	result.setVersion(b)
+	fmt.Printf("%d writers, %d readers, query mix: %s\n", p.T, m.Readers, mix)
+
+	var sumRecord, seconds float64
+	var all PipelineStats
+	reads := map[string]*readStats{}
+	startTime := time.Now()
+	for k := 0; k < p.Retry; k++ {
+		if err = beforeRound(b, p, k); err != nil {
+			break
+		}
+		stop := make(chan struct{})
+		wait := runReaders(b, p, m, mix, stop, reads)
+		var round RoundResult
+		var stats PipelineStats
+		err, round, stats = writeRound(b, p, k)
+		close(stop)
+		wait()
+		if p.Check {
+			result.Expected = stats.aggregates
+		}
+		all.add(stats)
+		seconds += round.Seconds
+		result.Rounds = append(result.Rounds, round)
+		if err != nil {
+			break
+		}
+		sumRecord += round.Throughput
+	}
+
+	if len(result.Rounds) > 0 && err == nil {
+		result.Throughput = sumRecord / float64(len(result.Rounds))
+		result.Latency = NewLatencyResult(all.Latency.Summary())
+		if all.QueueDelay != nil {
+			result.QueueDelay = NewLatencyResult(all.QueueDelay.Summary())
+		}
+		if all.RetryLatency != nil {
+			result.RetryLatency = NewLatencyResult(all.RetryLatency.Summary())
+		}
+	}
+	for _, name := range mix.names {
+		rs := reads[name]
+		if rs == nil {
+			rs = &readStats{latency: NewHistogram()}
+		}
+		// 查询项是随机选择的, Iterations 为平均每个客户端执行的次数
+		qr := QueryResult{Name: name, StartTime: startTime, Clients: m.Readers, Iterations: (int(rs.latency.Count()) + rs.errors) / m.Readers,
+			Errors: rs.errors, Seconds: seconds, Latency: NewLatencyResult(rs.latency.Summary())}
+		if seconds > 0 {
+			qr.QPS = float64(rs.latency.Count()) / seconds
+		}
+		result.Queries = append(result.Queries, qr)
+	}
+	PrintMixed(result)
+	return result.finish(err)
+}
+
+// PrintMixed 并列输出写入与各查询项的指标
+func PrintMixed(r *Result) {
+	fmt.Printf("======== %s mixed workload: write and query side by side ========\n", r.Target)
+	fmt.Printf("%-24s %16s %10s %10s %10s %8s\n", "", "records|queries/s", "p50(ms)", "p99(ms)", "max(ms)", "errors")
+	if r.Latency != nil {
+		fmt.Printf("%-24s %16.3f %10.3f %10.3f %10.3f %8s\n", "write batch", r.Throughput, r.Latency.P50, r.Latency.P99, r.Latency.Max, "-")
+	}
+	for _, q := range r.Queries {
+		label := queryLabels[q.Name]
+		if label == "" {
+			label = q.Name
+		}
+		fmt.Printf("%-24s %16.3f %10.3f %10.3f %10.3f %8d\n", label, q.QPS, q.Latency.P50, q.Latency.P99, q.Latency.Max, q.Errors)
+	}
+}
@@ -0,0 +1,449 @@
+package common
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MOBackend MatrixOne 写入与查询
+type MOBackend struct {
+	conf   *DBConfig
+	mode   string
+	tType  string // ts|tsPK|intPK
+	wType  string // insert|loadLine|loadFile|prepare
+	txc    int    // 每个事务提交的写入次数, 0 表示不开启事务
+	schema *Schema
+	dbList []*sql.DB
+
+	txList    []*sql.Tx
+	txCounter []int
+	// prepare 写入时每个客户端按行数缓存的预编译语句, 每个客户端只写一张表
+	stmtList []map[int]*sql.Stmt
+}
+
+// moMaxPlaceholders 一条预编译语句最多的占位符数, MySQL 协议中参数个数为 2 字节
+const moMaxPlaceholders = 65535
+
+// moPrepareBatch prepare 写入的一个请求, 按占位符上限拆分为多条多行 INSERT, 每条为按行展开的参数
+type moPrepareBatch struct {
+	chunks [][]interface{}
+}
+
+func NewMOBackend(conf *DBConfig, mode, tType, wType string, txc int, schema *Schema) *MOBackend {
+	return &MOBackend{
+		conf:   conf,
+		mode:   mode,
+		tType:  tType,
+		wType:  wType,
+		txc:    txc,
+		schema: schema,
+	}
+}
+
+func (m *MOBackend) Name() string {
+	return MO
+}
+
+func (m *MOBackend) Version() string {
+	_, version := QueryVersion(m.dbList[0], "select version()")
+	return version
+}
+
+// TxBatches txc 大于 0 时每 txc 次写入提交一次事务
+func (m *MOBackend) TxBatches() int {
+	return max(m.txc, 0)
+}
+
+// Retryable 事务写写冲突、死锁、锁等待超时和网络错误可以重试
+func (m *MOBackend) Retryable(err error) bool {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && (me.Number == 1205 || me.Number == 1213) {
+		return true
+	}
+	return IsTransientError(err) || errorContains(err, "w-w conflict", "txn need retry", "deadlock found")
+}
+
+// tableName 按设备写入时所有客户端写同一张宽表 d0
+func (m *MOBackend) tableName(worker int) string {
+	if m.schema.Devices > 0 {
+		return Database + "." + Table
+	}
+	return Database + "." + TableName(m.mode, m.conf.TablePrefix, worker)
+}
+
+func (m *MOBackend) tableCount(T1 int) int {
+	if m.mode == Multi && m.schema.Devices == 0 {
+		return T1
+	}
+	return 1
+}
+
+func (m *MOBackend) CheckArgs(p WriteParams) error {
+	var err error
+	// 校验mode值
+	if err = CheckMode(m.mode); err != nil {
+		return err
+	}
+	// 校验 type值：ts|tsPK|intPK
+	if m.tType != Ts && m.tType != TsPK && m.tType != IntPK {
+		err = errors.New(fmt.Sprintf("unrecognized tType value:%s, required to be ts|tsPK|intPK, default ts", m.tType))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+
+	// 校验 wType ：insert|loadLine|loadFile|prepare
+	if m.wType != Insert && m.wType != LoadLine && m.wType != LoadFile && m.wType != Prepare {
+		err = errors.New(fmt.Sprintf("unrecognized wType value:%s, required to be insert|loadLine|loadFile|prepare, default loadLine", m.wType))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+
+	if m.wType == LoadFile {
+		if m.conf.LoadFilePath == "" {
+			err = errors.New(fmt.Sprintf("loadFilePath cannot be empty, when wType is loadFile"))
+			fmt.Printf("%v\n", err)
+			return err
+		} else {
+			// 结尾加上/
+			m.conf.LoadFilePath = strings.TrimSuffix(m.conf.LoadFilePath, "/") + "/"
+			// 要求n必须是r值的的整倍数
+			a := p.N / p.R
+			b := p.N % p.R
+			if a <= 0 || b != 0 {
+				err = errors.New("loadFile 写入模式下, 要求n必须是r的整倍数")
+				fmt.Println(err)
+				return err
+			}
+		}
+		// 服务端文件中的数据不是生成的数据, 只能校验记录数, 也不能校验查询结果
+		if p.Verify == VerifyChecksum {
+			err = errors.New("verify checksum is not supported when wType is loadFile, use verify count")
+			fmt.Printf("%v\n", err)
+			return err
+		}
+		if p.Check {
+			err = errors.New("check is not supported when wType is loadFile, the loaded file is not the generated data")
+			fmt.Printf("%v\n", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Connect prepare 写入依赖服务端预编译(COM_STMT_PREPARE/COM_STMT_EXECUTE), 因此显式关闭 interpolateParams
+func (m *MOBackend) Connect(T1 int) error {
+	encodedUsername := url.QueryEscape(m.conf.User)
+	dsn := encodedUsername + ":" + m.conf.Password + "@tcp(" + m.conf.Host + ":" + m.conf.Port + ")/?charset=utf8mb4&parseTime=True&loc=Local&interpolateParams=false"
+	err, dbList := GetDbConn(T1, dsn)
+	m.dbList = dbList
+	m.txList = make([]*sql.Tx, T1)
+	m.txCounter = make([]int, T1)
+	m.stmtList = make([]map[int]*sql.Stmt, T1)
+	for i := range m.stmtList {
+		m.stmtList[i] = map[int]*sql.Stmt{}
+	}
+	return err
+}
+
+func (m *MOBackend) InitTable(T1 int) error {
+	db := m.dbList[0]
+	var err error
+	dropDatabaseSQL := fmt.Sprintf("DROP DATABASE IF EXISTS %s", Database)
+	_, err = db.Exec(dropDatabaseSQL)
+	if err != nil {
+		fmt.Printf("drop database  %s fail:%v \n", Database, err)
+		return err
+	}
+
+	ctDatabaseSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", Database)
+	_, err = db.Exec(ctDatabaseSQL)
+	if err != nil {
+		fmt.Printf("create database  %s fail:%v \n", Database, err)
+		return err
+	}
+
+	var ctTableTempte string
+	// 标签列作为普通列放在数据列之后
+	columnDefs := ColumnDefs(MO, m.schema.AllColumns())
+	// 按设备写入时各设备的时间戳会重复, 主键为 (device_id, ts)
+	primaryKey := "ts"
+	if m.schema.Devices > 0 {
+		primaryKey = DeviceColumn + ", ts"
+	}
+	// ts：表示无主键时序表，tsPK：表示有主键时序表，intPK：表示主键为int类型的普通表
+	switch m.tType {
+	case Ts:
+		ctTableTempte = "create table if not exists %s (ts TIMESTAMP(3) not null, " + columnDefs + ");"
+	case TsPK:
+		ctTableTempte = "create table if not exists %s (ts TIMESTAMP(3) not null, " + columnDefs + ", PRIMARY KEY (" + primaryKey + "));"
+	case IntPK:
+		ctTableTempte = "create table if not exists %s (ts bigint not null, " + columnDefs + ", PRIMARY KEY (" + primaryKey + "));"
+	default:
+		err = errors.New(fmt.Sprintf("invalid tType value:%s, required to be ts|tsPK|intPK", m.tType))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+
+	tableCount := m.tableCount(T1)
+	for z := 0; z < tableCount; z++ {
+		tableName := m.tableName(z)
+		ctTableSQL := fmt.Sprintf(ctTableTempte, tableName)
+		_, err = db.Exec(ctTableSQL)
+		if err != nil {
+			fmt.Printf("create table %s fail:%v \n", tableName, err)
+			return err
+		}
+	}
+
+	fmt.Printf("Initialize database and table completed.\n")
+	return nil
+}
+
+func (m *MOBackend) TruncateTables(T1 int) error {
+	tableCount := m.tableCount(T1)
+	for z := 0; z < tableCount; z++ {
+		tableName := m.tableName(z)
+		delSql := fmt.Sprintf("truncate table %s", tableName)
+		if err := ExecSql(m.dbList[0], delSql); err != nil {
+			fmt.Printf("truncate table %s fail:%v \n", tableName, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordTable 按设备写入时为宽表 d0, 否则为第 worker 个客户端写入的表
+func (m *MOBackend) RecordTable(worker int, rec Record) string {
+	return m.tableName(worker)
+}
+
+// QueryChecksum intPK 表的 ts 为毫秒时间戳, 其他表的 ts 为 TIMESTAMP(3)
+func (m *MOBackend) QueryChecksum(table string, sums bool) (error, TableChecksum) {
+	tsExpr := fmt.Sprintf("unix_timestamp(ts)*1000 - %d", StartTimestamp)
+	if m.tType == IntPK {
+		tsExpr = fmt.Sprintf("ts - %d", StartTimestamp)
+	}
+	return QueryChecksum(m.dbList[0], table, tsExpr, m.schema.Columns, sums)
+}
+
+// Queried 查询测试读取 d0
+func (m *MOBackend) Queried(table string) bool {
+	return table == Database+"."+Table
+}
+
+func (m *MOBackend) EncodeBatch(worker int, records []Record) (error, interface{}) {
+	tableName := m.tableName(worker)
+	if m.wType == LoadFile {
+		// LOAD DATA INFILE 'yourfilepath' INTO TABLE xx CHARACTER SET utf8;
+		return nil, fmt.Sprintf("LOAD DATA INFILE '%s%d.csv' INTO TABLE %s;", m.conf.LoadFilePath, len(records), tableName)
+	}
+	if m.wType == Prepare {
+		return nil, m.encodePrepare(worker, records)
+	}
+
+	//var buffer strings.Builder
+	var buffer bytes.Buffer
+	// 定义 insert、loadLine 两种不同的写数据sql语句前缀
+	if m.wType == Insert {
+		buffer.WriteString(fmt.Sprintf("INSERT INTO %s VALUES", tableName))
+	} else {
+		buffer.WriteString("load data inline format='csv',data=$XXX$")
+	}
+
+	tags := m.schema.TableTags(m.mode, worker)
+	for i, rec := range records {
+		// 当表为主键为int类型的普通表时，ts值取时间戳
+		var tsValue string
+		if m.tType == IntPK {
+			tsValue = strconv.FormatInt(rec.Ts, 10)
+		} else if m.wType == Insert {
+			tsValue = "'" + rec.TsString() + "'"
+		} else {
+			tsValue = rec.TsString()
+		}
+
+		// 拼接 insert、loadLine 两种不同的写数据sql
+		if m.wType == Insert {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString("(" + tsValue)
+			for _, v := range m.schema.Row(rec, tags) {
+				buffer.WriteString(", " + SqlValue(v))
+			}
+			buffer.WriteString(")")
+		} else {
+			if i > 0 {
+				buffer.WriteString(" \n ")
+			}
+			buffer.WriteString(tsValue)
+			for _, v := range m.schema.Row(rec, tags) {
+				buffer.WriteString("," + CsvValue(v))
+			}
+		}
+	}
+
+	if m.wType == LoadLine {
+		buffer.WriteString(fmt.Sprintf(" $XXX$ into table %s;", tableName))
+	}
+	return nil, buffer.String()
+}
+
+// columnCount 每行的列数: ts、数据列与标签列
+func (m *MOBackend) columnCount() int {
+	return len(m.schema.AllColumns()) + 1
+}
+
+// encodePrepare 将记录按行展开为参数, 每条语句的行数使占位符数不超过 moMaxPlaceholders
+func (m *MOBackend) encodePrepare(worker int, records []Record) *moPrepareBatch {
+	cols := m.columnCount()
+	maxRows := moMaxPlaceholders / cols
+	tags := m.schema.TableTags(m.mode, worker)
+	batch := &moPrepareBatch{}
+	for start := 0; start < len(records); start += maxRows {
+		end := start + maxRows
+		if end > len(records) {
+			end = len(records)
+		}
+		args := make([]interface{}, 0, (end-start)*cols)
+		for _, rec := range records[start:end] {
+			// 当表为主键为int类型的普通表时，ts值取时间戳
+			if m.tType == IntPK {
+				args = append(args, rec.Ts)
+			} else {
+				args = append(args, rec.TsString())
+			}
+			args = append(args, m.schema.Row(rec, tags)...)
+		}
+		batch.chunks = append(batch.chunks, args)
+	}
+	return batch
+}
+
+// prepareStmt 返回第 worker 个客户端写入 rows 行的预编译语句, 首次使用时在服务端预编译
+func (m *MOBackend) prepareStmt(worker, rows int) (error, *sql.Stmt) {
+	if stmt := m.stmtList[worker][rows]; stmt != nil {
+		return nil, stmt
+	}
+	row := "(?" + strings.Repeat(",?", m.columnCount()-1) + ")"
+	sql1 := fmt.Sprintf("INSERT INTO %s VALUES %s", m.tableName(worker), row+strings.Repeat(","+row, rows-1))
+	stmt, err := m.dbList[worker].Prepare(sql1)
+	if err != nil {
+		fmt.Printf("prepare insert statement of %d rows fail:%v\n", rows, err)
+		return err, nil
+	}
+	m.stmtList[worker][rows] = stmt
+	return nil, stmt
+}
+
+// execPrepare 逐条执行预编译语句, tx 不为空时在事务中执行。
+// 不使用事务而请求拆分为多条语句时, 在一个事务中执行全部语句, 失败时整个请求回滚, 重试时不会重复写入
+func (m *MOBackend) execPrepare(worker int, tx *sql.Tx, batch *moPrepareBatch) error {
+	if tx == nil && len(batch.chunks) > 1 {
+		tx, err := m.dbList[worker].Begin()
+		if err != nil {
+			fmt.Printf("begin 'prepare' tx err: %v \n", err)
+			return err
+		}
+		if err = m.execPrepare(worker, tx, batch); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+	for _, args := range batch.chunks {
+		err, stmt := m.prepareStmt(worker, len(args)/m.columnCount())
+		if err != nil {
+			return err
+		}
+		if tx != nil {
+			// 事务所在的连接上已预编译过该语句时直接复用, 否则在该连接上重新预编译, 随事务结束关闭
+			stmt = tx.Stmt(stmt)
+		}
+		if _, err = stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MOBackend) WriteBatch(worker int, batch interface{}) error {
+	db := m.dbList[worker]
+	// 当txc值大于0时，则开启事务提交写入，否则不使用事务
+	if m.txc <= 0 {
+		if m.wType == Prepare {
+			return m.execPrepare(worker, nil, batch.(*moPrepareBatch))
+		}
+		return ExecSql(db, batch.(string))
+	}
+
+	var err error
+	if m.txList[worker] == nil {
+		m.txList[worker], err = db.Begin()
+		if err != nil {
+			fmt.Printf("begin 'insert into' tx err: %v \n", err)
+			return err
+		}
+	}
+	// 执行事务写入, 失败时回滚事务, 本事务中之前写入的请求也一并回滚
+	if m.wType == Prepare {
+		err = m.execPrepare(worker, m.txList[worker], batch.(*moPrepareBatch))
+	} else {
+		err = TxExecSql(m.txList[worker], batch.(string))
+	}
+	if err != nil {
+		m.txList[worker].Rollback()
+		m.txList[worker] = nil
+		m.txCounter[worker] = 0
+		return err
+	}
+	// 累计写入txc次后，提交事务
+	m.txCounter[worker]++
+	if m.txCounter[worker] == m.txc {
+		return m.Flush(worker)
+	}
+	return nil
+}
+
+func (m *MOBackend) Flush(worker int) error {
+	tx := m.txList[worker]
+	if tx == nil {
+		return nil
+	}
+	m.txList[worker] = nil
+	m.txCounter[worker] = 0
+	return tx.Commit()
+}
+
+func (m *MOBackend) Queries() []string {
+	return []string{QueryNameCount, QueryNameSelect, QueryNamePoint, QueryNameAvg, QueryNameSum, QueryNameMax, QueryNameMin, QueryNameTimeWindow, QueryNameLatest}
+}
+
+func (m *MOBackend) RunQuery(worker int, name string) (error, float64) {
+	tableName := Database + "." + Table
+	column := m.schema.AggColumn()
+	var timeWindowSql string
+	if column != "" {
+		timeWindowSql = fmt.Sprintf("select _wstart, _wend, max(`%s`), min(`%s`) from %s interval(ts, 60, minute) sliding(60, minute)", column, column, tableName)
+	}
+	return RunSqlQuery(m.dbList[worker], name, tableName, m.conf.PointQueryTsCondition, column, timeWindowSql)
+}
+
+func (m *MOBackend) Close() {
+	for _, stmts := range m.stmtList {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}
+	for _, conn := range m.dbList {
+		conn.Close()
+	}
+}
@@ -0,0 +1,580 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const DefaultQueueDepth = 8
+
+// 写入请求失败时的处理方式
+const (
+	OnErrorFail     = "fail"     // 所有客户端停止写入, 本轮测试失败
+	OnErrorContinue = "continue" // 跳过该请求, 计入失败的记录数
+	// 可重试的错误按 Backoff 退避重试, 仍失败时跳过; 不可重试的错误同 fail
+	OnErrorRetry = "retry"
+)
+
+// CheckOnError 校验写入失败的处理方式及重试策略
+func CheckOnError(onError string, backoff RetryPolicy) error {
+	switch onError {
+	case "", OnErrorFail, OnErrorContinue:
+		return nil
+	case OnErrorRetry:
+		err := backoff.check()
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
+		return err
+	}
+	err := errors.New(fmt.Sprintf("unrecognized onError value:%s, required to be fail|continue|retry", onError))
+	fmt.Printf("%v\n", err)
+	return err
+}
+
+// 写入请求的到达方式
+const (
+	ArrivalClosed  = "closed"  // 闭环: 上一个请求返回后立即发送下一个, 指定 Rate 时按 Rate 限速
+	ArrivalFixed   = "fixed"   // 开环: 每个客户端按固定间隔安排发送时间, 与请求何时返回无关
+	ArrivalPoisson = "poisson" // 开环: 每个客户端按泊松过程(指数分布的间隔)安排发送时间
+)
+
+// CheckArrival 开环写入需要指定目标速度
+func CheckArrival(arrival string, rate float64) error {
+	switch arrival {
+	case "", ArrivalClosed:
+		return nil
+	case ArrivalFixed, ArrivalPoisson:
+		if rate <= 0 {
+			err := errors.New(fmt.Sprintf("arrival %s requires a target rate", arrival))
+			fmt.Printf("%v\n", err)
+			return err
+		}
+		return nil
+	}
+	err := errors.New(fmt.Sprintf("unrecognized arrival value:%s, required to be closed|fixed|poisson", arrival))
+	fmt.Printf("%v\n", err)
+	return err
+}
+
+// schedule 开环写入时一个客户端的请求计划发送时间
+type schedule struct {
+	interval time.Duration // 平均间隔
+	rng      *rand.Rand    // 不为 nil 时间隔服从指数分布
+	next     time.Time
+}
+
+// newSchedule 第 worker 个客户端的发送计划, 每个客户端的速度为 Rate/T; 闭环时返回 nil
+func newSchedule(p WriteParams, worker int) *schedule {
+	if p.Arrival != ArrivalFixed && p.Arrival != ArrivalPoisson {
+		return nil
+	}
+	s := &schedule{interval: time.Duration(float64(time.Second) * float64(p.R*p.T) / p.Rate), next: time.Now()}
+	if p.Arrival == ArrivalPoisson {
+		s.rng = rand.New(rand.NewSource(p.Gen.Seed + int64(worker)))
+	}
+	return s
+}
+
+// intended 返回下一个请求的计划发送时间
+func (s *schedule) intended() time.Time {
+	send := s.next
+	interval := s.interval
+	if s.rng != nil {
+		interval = time.Duration(s.rng.ExpFloat64() * float64(s.interval))
+	}
+	s.next = s.next.Add(interval)
+	return send
+}
+
+// PipelineStats 一轮写入中数据生成端(客户端)与写入端(服务端)的耗时和等待时间, 为所有协程之和
+type PipelineStats struct {
+	GenTime   time.Duration // 生成、编码数据的耗时
+	GenBlock  time.Duration // 队列已满, 生成端等待写入端的时间
+	WriteTime time.Duration // 执行写入请求的耗时
+	WriteWait time.Duration // 队列为空, 写入端等待生成端的时间
+	// 每次写入请求的耗时, 开环写入时从计划发送时间算起, 包含排队时间
+	Latency *Histogram
+	// 开环写入时每个请求实际发送时间晚于计划发送时间的排队时间, 闭环写入时为 nil
+	QueueDelay *Histogram
+	// OnError 为 retry 时每个重试过的请求从第一次失败到最终成功或放弃的时间, 否则为 nil
+	RetryLatency *Histogram
+	Rows         int              // 写入成功的记录数
+	FailedRows   int              // 写入失败的记录数
+	Skipped      int              // 数据库实现 SkipNullBackend 时跳过的全部列为 NULL 的记录数
+	Errors       int              // 失败的写入请求数
+	Retries      int              // 写入请求的重试次数
+	Retried      int              // 重试过的写入请求数
+	Intervals    []IntervalResult // 指定 Interval 时每个区间的写入速度与耗时
+	Workers      []WorkerResult   // 每个客户端写入成功、失败的记录数
+
+	expected   checksums   // 指定 Verify 时写入成功的记录按表累加的期望结果
+	aggregates *Aggregates // 指定 Check 时查询测试读取的记录中写入成功的记录的聚合值
+}
+
+// WorkerResult 一个客户端在一轮写入中的结果
+type WorkerResult struct {
+	Worker     int `json:"worker"`
+	Rows       int `json:"rows"`
+	FailedRows int `json:"failedRows"`
+	Errors     int `json:"errors"`
+	Retries    int `json:"retries"`
+	Retried    int `json:"retried"` // 重试过的请求数
+}
+
+func (s *PipelineStats) add(o PipelineStats) {
+	s.GenTime += o.GenTime
+	s.GenBlock += o.GenBlock
+	s.WriteTime += o.WriteTime
+	s.WriteWait += o.WriteWait
+	s.Rows += o.Rows
+	s.FailedRows += o.FailedRows
+	s.Skipped += o.Skipped
+	s.Errors += o.Errors
+	s.Retries += o.Retries
+	s.Retried += o.Retried
+	if o.Latency != nil {
+		if s.Latency == nil {
+			s.Latency = NewHistogram()
+		}
+		s.Latency.Merge(o.Latency)
+	}
+	if o.QueueDelay != nil {
+		if s.QueueDelay == nil {
+			s.QueueDelay = NewHistogram()
+		}
+		s.QueueDelay.Merge(o.QueueDelay)
+	}
+	if o.RetryLatency != nil {
+		if s.RetryLatency == nil {
+			s.RetryLatency = NewHistogram()
+		}
+		s.RetryLatency.Merge(o.RetryLatency)
+	}
+}
+
+// Bottleneck 写入端等待数据的时间更长说明客户端生成数据跟不上, 否则瓶颈在服务端
+func (s *PipelineStats) Bottleneck() string {
+	if s.WriteWait > s.GenBlock {
+		return "client"
+	}
+	return "server"
+}
+
+func (s *PipelineStats) Print() {
+	fmt.Printf("pipeline: generate %.3fs (blocked on full queue %.3fs), write %.3fs (waited on empty queue %.3fs), bottleneck: %s\n",
+		s.GenTime.Seconds(), s.GenBlock.Seconds(), s.WriteTime.Seconds(), s.WriteWait.Seconds(), s.Bottleneck())
+	if s.QueueDelay != nil {
+		fmt.Printf("batch latency (from scheduled send time): %s\n", s.Latency)
+		fmt.Printf("queue delay: %s\n", s.QueueDelay)
+	} else if s.Latency != nil {
+		fmt.Printf("batch latency: %s\n", s.Latency)
+	}
+	if s.Retried > 0 {
+		fmt.Printf("retry latency: %s\n", s.RetryLatency)
+	}
+}
+
+// batchCount 每个客户端的 n1 条记录按每次 r1 条拆分的请求数
+func batchCount(n1, r1 int) int {
+	subNum := n1 / r1
+	if n1%r1 > 0 {
+		subNum += 1
+	}
+	return subNum
+}
+
+// queuedBatch 队列中的一个写入请求
+type queuedBatch struct {
+	data       interface{} // EncodeBatch 编码的请求
+	rows       int         // 记录数
+	expected   checksums   // 指定 Verify 时请求中的记录按表累加的结果
+	aggregates *Aggregates // 指定 Check 时请求中由查询测试读取的记录的聚合值
+}
+
+// generate 生成第 worker 个客户端的全部数据, 每 r1 条编码为一个写入请求放入 queue, done 关闭时提前退出。
+// 指定 Duration 时不断生成数据, 直到 stop 关闭
+func generate(b Backend, p WriteParams, worker int, queue chan<- queuedBatch, done, stop <-chan struct{}, stats *PipelineStats) error {
+	defer close(queue)
+
+	gen := p.Gen.NewGenerator(worker)
+	vb, _ := b.(VerifyBackend)
+	qb, _ := b.(QueriedBackend)
+	verify := vb != nil && p.Verify != "" && p.Verify != VerifyNone
+	check := qb != nil && p.Check
+	sb, _ := b.(SkipNullBackend)
+	skipNull := sb != nil && sb.SkipAllNull()
+	startTimestamp := StartTimestamp + int64(worker*p.N)
+	tsStep := int64(1)
+	if p.Duration > 0 {
+		// 写入的记录数不确定, 各客户端的时间戳交错排列以免重复
+		startTimestamp = StartTimestamp + int64(worker)
+		tsStep = int64(p.T)
+	}
+	// 按设备(或多表)写入时轮流为负责的每个设备生成一条记录, 每个设备的时间戳从 StartTimestamp 开始依次递增
+	firstDevice, devices, rows := p.workload(worker)
+	var row int
+	subNum := batchCount(rows, p.R)
+	rem := rows % p.R
+	dataSize := p.R
+	for j := 0; p.Duration > 0 || j < subNum; j++ {
+		if p.Duration == 0 && j == subNum-1 && rem > 0 {
+			dataSize = rem
+		}
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		genStart := time.Now()
+		records := make([]Record, 0, dataSize)
+		for i := 0; i < dataSize; i++ {
+			if devices > 0 {
+				records = append(records, gen.Next(StartTimestamp+int64(row/devices), firstDevice+row%devices))
+				row++
+				continue
+			}
+			records = append(records, gen.Next(startTimestamp, 0))
+			startTimestamp += tsStep
+		}
+		if skipNull {
+			written := records[:0]
+			for _, rec := range records {
+				if rec.AllNull() {
+					stats.Skipped++
+					continue
+				}
+				written = append(written, rec)
+			}
+			records = written
+			if len(records) == 0 {
+				stats.GenTime += time.Since(genStart)
+				continue
+			}
+		}
+		err, data := b.EncodeBatch(worker, records)
+		if err != nil {
+			fmt.Printf("encode %s batch fail:%v\n", b.Name(), err)
+			return err
+		}
+		batch := queuedBatch{data: data, rows: len(records)}
+		if verify {
+			batch.expected = checksums{}
+		}
+		if check {
+			batch.aggregates = newAggregates(p.Gen.Schema)
+		}
+		if verify || check {
+			for _, rec := range records {
+				table := vb.RecordTable(worker, rec)
+				if verify {
+					batch.expected.add(table, rec, p.Gen.Schema, p.Verify == VerifyChecksum)
+				}
+				if batch.aggregates != nil && qb.Queried(table) {
+					batch.aggregates.add(rec)
+				}
+			}
+		}
+		stats.GenTime += time.Since(genStart)
+
+		// 队列未满时直接放入, 否则计算等待时间
+		select {
+		case queue <- batch:
+			continue
+		default:
+		}
+		blockStart := time.Now()
+		select {
+		case queue <- batch:
+			stats.GenBlock += time.Since(blockStart)
+		case <-done:
+			return nil
+		case <-stop:
+			return nil
+		}
+	}
+	return nil
+}
+
+// writer 一个客户端的写入协程
+type writer struct {
+	b      Backend
+	p      WriteParams
+	worker int
+	pace   *pacer            // 不为 nil 时按其安排的时间发送请求
+	sched  *schedule         // 不为 nil 时为开环写入, 在计划发送时间发送, 已落后时立即发送
+	rec    *intervalRecorder // 每个请求的记录数与耗时同时记入 rec
+	abort  <-chan struct{}   // OnError 为 fail 时任一客户端写入失败后关闭
+	fail   func()            // 写入失败且 OnError 为 fail 时调用, 通知所有客户端停止
+	rng    *rand.Rand        // 重试等待时间的随机抖动
+	stats  PipelineStats
+
+	txBatches int            // 数据库实现 TxBackend 时每个事务的请求数, 0 表示不使用事务
+	pending   []pendingBatch // 已写入但所在事务尚未提交的请求
+}
+
+// pendingBatch 已写入、等待事务提交的请求及其耗时
+type pendingBatch struct {
+	batch queuedBatch
+	spend time.Duration
+}
+
+// consume 从 queue 中取出请求写入数据库, 直到 queue 关闭或其他客户端写入失败
+func (w *writer) consume(queue <-chan queuedBatch) error {
+	stats := &w.stats
+	for {
+		var batch queuedBatch
+		var ok bool
+		select {
+		case batch, ok = <-queue:
+		default:
+			waitStart := time.Now()
+			batch, ok = <-queue
+			stats.WriteWait += time.Since(waitStart)
+		}
+		if !ok {
+			break
+		}
+		select {
+		case <-w.abort:
+			// 未提交的事务不会再提交
+			w.discardPending()
+			return nil
+		default:
+		}
+
+		var intended time.Time
+		if w.sched != nil {
+			intended = w.sched.intended()
+			time.Sleep(time.Until(intended))
+		} else {
+			w.pace.wait()
+		}
+		writeStart := time.Now()
+		err, fatal := w.write(batch)
+		spend := time.Since(writeStart)
+		stats.WriteTime += spend
+		if w.sched != nil {
+			stats.QueueDelay.Record(writeStart.Sub(intended))
+			spend = time.Since(intended)
+		}
+		if err != nil {
+			stats.Errors++
+			w.discard(batch)
+			fmt.Printf("worker %d write %d records fail:%v\n", w.worker, batch.rows, err)
+			// 事务中之前写入的请求随事务一起回滚
+			w.discardPending()
+			if fatal || w.p.OnError == "" || w.p.OnError == OnErrorFail {
+				w.fail()
+				return err
+			}
+			continue
+		}
+		stats.Latency.Record(spend)
+		if w.txBatches == 0 {
+			w.commit(batch, spend)
+			continue
+		}
+		// 事务中第 txBatches 个请求写入成功时事务已提交
+		w.pending = append(w.pending, pendingBatch{batch: batch, spend: spend})
+		if len(w.pending) == w.txBatches {
+			w.commitPending()
+		}
+	}
+	if err, fatal := w.flush(); err != nil {
+		stats.Errors++
+		fmt.Printf("worker %d flush fail:%v\n", w.worker, err)
+		w.discardPending()
+		if fatal || w.p.OnError == "" || w.p.OnError == OnErrorFail {
+			return err
+		}
+	}
+	w.commitPending()
+	return nil
+}
+
+// commit 计入写入成功的请求的记录数与期望结果
+func (w *writer) commit(batch queuedBatch, spend time.Duration) {
+	w.stats.Rows += batch.rows
+	w.stats.expected.merge(batch.expected)
+	w.stats.aggregates.merge(batch.aggregates)
+	w.rec.record(batch.rows, spend)
+}
+
+// discard 计入写入失败的请求的记录数, 写入失败的表也要校验, 期望的记录数为 0
+func (w *writer) discard(batch queuedBatch) {
+	for table := range batch.expected {
+		w.stats.expected.table(table)
+	}
+	w.stats.FailedRows += batch.rows
+}
+
+// commitPending 事务提交后计入事务中的全部请求
+func (w *writer) commitPending() {
+	for _, pb := range w.pending {
+		w.commit(pb.batch, pb.spend)
+	}
+	w.pending = nil
+}
+
+// discardPending 事务回滚后事务中的全部请求计为写入失败
+func (w *writer) discardPending() {
+	if len(w.pending) == 0 {
+		return
+	}
+	var rows int
+	for _, pb := range w.pending {
+		w.discard(pb.batch)
+		rows += pb.batch.rows
+	}
+	fmt.Printf("worker %d transaction rolled back, %d records of %d earlier requests lost\n", w.worker, rows, len(w.pending))
+	w.pending = nil
+}
+
+// write 执行一个写入请求, 失败时按 retry 重试
+func (w *writer) write(batch queuedBatch) (err error, fatal bool) {
+	return w.retry(func() error {
+		return w.b.WriteBatch(w.worker, batch.data)
+	})
+}
+
+// flush 提交未结束的事务, 失败时按 retry 重试
+func (w *writer) flush() (err error, fatal bool) {
+	return w.retry(func() error {
+		return w.b.Flush(w.worker)
+	})
+}
+
+// retry 执行 send。OnError 为 retry 时, 可重试的错误按 Backoff 退避后重试, 最多重试 MaxRetries 次;
+// 以事务写入时失败的事务已整个回滚, 每次重试前先重新写入事务中之前的请求。
+// fatal 表示最后的错误不可重试, 本轮写入应当停止
+func (w *writer) retry(send func() error) (err error, fatal bool) {
+	err = send()
+	if err == nil || w.p.OnError != OnErrorRetry {
+		return err, false
+	}
+	if !isRetryable(w.b, err) {
+		fmt.Printf("worker %d write fail, not retryable:%v\n", w.worker, err)
+		return err, true
+	}
+
+	retryStart := time.Now()
+	backoff := w.p.Backoff
+	for i := 1; i <= backoff.MaxRetries; i++ {
+		delay := backoff.Delay(i, w.rng)
+		fmt.Printf("worker %d write fail, retry %d/%d after %v:%v\n", w.worker, i, backoff.MaxRetries, delay, err)
+		w.stats.Retries++
+		time.Sleep(delay)
+		if err = w.replay(); err == nil {
+			if err = send(); err == nil {
+				break
+			}
+		}
+		if !isRetryable(w.b, err) {
+			fatal = true
+			break
+		}
+	}
+	w.stats.Retried++
+	w.stats.RetryLatency.Record(time.Since(retryStart))
+	return err, fatal
+}
+
+// replay 重新写入已回滚的事务中之前写入成功的请求, 请求数少于 txBatches, 不会提交事务
+func (w *writer) replay() error {
+	for _, pb := range w.pending {
+		if err := w.b.WriteBatch(w.worker, pb.batch.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPipeline 执行一轮写入: 每个客户端由一个生成协程经容量为 QueueDepth 的队列向写入协程供数据,
+// 闭环指定 Rate 时所有写入协程共用一个按 Rate/R 个请求每秒的节奏发送请求, 开环时每个写入协程按各自的计划发送。
+// 指定 Duration 时写入到时间结束为止, 指定 Interval 时每隔 Interval 输出一次该区间的写入速度与耗时。
+// 写入失败时按 OnError 处理, 返回第一个生成错误或导致停止的写入错误
+func runPipeline(b Backend, p WriteParams) (PipelineStats, error) {
+	queueDepth := p.QueueDepth
+	if queueDepth <= 0 {
+		queueDepth = DefaultQueueDepth
+	}
+
+	var pace *pacer
+	if p.Arrival == "" || p.Arrival == ArrivalClosed {
+		pace = newPacer(p.Rate / float64(p.R))
+	}
+
+	// 时间结束或写入失败时关闭 stop, 生成协程停止生成数据
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	halt := func() { stopOnce.Do(func() { close(stop) }) }
+	if p.Duration > 0 {
+		timer := time.AfterFunc(p.Duration, halt)
+		defer timer.Stop()
+	}
+	abort := make(chan struct{})
+	var abortOnce sync.Once
+	fail := func() {
+		abortOnce.Do(func() { close(abort) })
+		halt()
+	}
+	rec := newIntervalRecorder(p.Interval)
+
+	genStats := make([]PipelineStats, p.T)
+	writers := make([]*writer, p.T)
+	errs := make([]error, 2*p.T)
+	var wg sync.WaitGroup
+	for j := 0; j < p.T; j++ {
+		w := &writer{b: b, p: p, worker: j, pace: pace, sched: newSchedule(p, j), rec: rec, abort: abort, fail: fail}
+		if tb, ok := b.(TxBackend); ok {
+			w.txBatches = tb.TxBatches()
+		}
+		w.stats.Latency = NewHistogram()
+		w.stats.expected = checksums{}
+		w.stats.aggregates = newAggregates(p.Gen.Schema)
+		if w.sched != nil {
+			w.stats.QueueDelay = NewHistogram()
+		}
+		if p.OnError == OnErrorRetry {
+			w.rng = rand.New(rand.NewSource(p.Gen.Seed + int64(j)))
+			w.stats.RetryLatency = NewHistogram()
+		}
+		writers[j] = w
+		queue := make(chan queuedBatch, queueDepth)
+		done := make(chan struct{})
+		wg.Add(2)
+		go func(worker int) {
+			defer wg.Done()
+			errs[2*worker] = generate(b, p, worker, queue, done, stop, &genStats[worker])
+		}(j)
+		go func(worker int) {
+			defer wg.Done()
+			// 写入协程退出后通知生成协程停止
+			defer close(done)
+			errs[2*worker+1] = writers[worker].consume(queue)
+		}(j)
+	}
+	wg.Wait()
+
+	stats := PipelineStats{expected: checksums{}, aggregates: newAggregates(p.Gen.Schema)}
+	stats.Intervals = rec.finish()
+	for j := 0; j < p.T; j++ {
+		stats.add(genStats[j])
+		stats.add(writers[j].stats)
+		stats.expected.merge(writers[j].stats.expected)
+		stats.aggregates.merge(writers[j].stats.aggregates)
+		ws := writers[j].stats
+		stats.Workers = append(stats.Workers, WorkerResult{Worker: j, Rows: ws.Rows, FailedRows: ws.FailedRows, Errors: ws.Errors, Retries: ws.Retries, Retried: ws.Retried})
+	}
+	for _, err := range errs {
+		if err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"sync"
+	"testing"
+)
+
+// skipNullBackend 记录写入的记录数与其中全部列为 NULL 的记录数
+type skipNullBackend struct {
+	mu      sync.Mutex
+	rows    int
+	allNull int
+}
+
+func (s *skipNullBackend) Name() string                  { return "skipNull" }
+func (s *skipNullBackend) CheckArgs(p WriteParams) error { return nil }
+func (s *skipNullBackend) Connect(T1 int) error          { return nil }
+func (s *skipNullBackend) InitTable(T1 int) error        { return nil }
+func (s *skipNullBackend) TruncateTables(T1 int) error   { return nil }
+func (s *skipNullBackend) EncodeBatch(worker int, records []Record) (error, interface{}) {
+	return nil, records
+}
+func (s *skipNullBackend) WriteBatch(worker int, batch interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range batch.([]Record) {
+		s.rows++
+		if rec.AllNull() {
+			s.allNull++
+		}
+	}
+	return nil
+}
+func (s *skipNullBackend) Flush(worker int) error                            { return nil }
+func (s *skipNullBackend) Queries() []string                                 { return nil }
+func (s *skipNullBackend) RunQuery(worker int, name string) (error, float64) { return nil, 0 }
+func (s *skipNullBackend) Close()                                            {}
+func (s *skipNullBackend) SkipAllNull() bool                                 { return true }
+
+func TestPipelineSkipsAllNullRecords(t *testing.T) {
+	schema := &Schema{Columns: []Column{
+		{Name: "current", Type: TypeFloat, Nullable: true, NullRate: 0.8},
+		{Name: "voltage", Type: TypeInt, Nullable: true, NullRate: 0.8},
+	}}
+	if err := schema.init(); err != nil {
+		t.Fatal(err)
+	}
+	b := &skipNullBackend{}
+	p := WriteParams{R: 10, T: 2, N: 1000, Gen: &GeneratorSpec{Seed: DefaultSeed, Schema: schema}}
+	stats, err := runPipeline(b, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.allNull != 0 {
+		t.Errorf("%d all-NULL records were written", b.allNull)
+	}
+	if stats.Skipped == 0 || stats.Rows != b.rows || stats.Rows+stats.Skipped != p.T*p.N || stats.FailedRows != 0 {
+		t.Errorf("rows=%d skipped=%d failed=%d written=%d, want skipped>0 and rows+skipped=%d", stats.Rows, stats.Skipped, stats.FailedRows, b.rows, p.T*p.N)
+	}
+}
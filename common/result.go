@@ -0,0 +1,335 @@
+package common
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Result 一次写入或查询测试的结果, 可输出为 json 或 csv
+type Result struct {
+	Id         string            `json:"id,omitempty"`    // 保存到历史记录时生成
+	Label      string            `json:"label,omitempty"` // 如构建版本、nightly, 用于在历史记录中查找基线
+	Phase      string            `json:"phase,omitempty"` // 场景中的阶段名
+	Command    string            `json:"command"`         // write|query|saturate|mixed
+	Target     string            `json:"target"`
+	Version    string            `json:"version"` // 服务端版本
+	Params     map[string]string `json:"params"`
+	StartTime  time.Time         `json:"startTime"`
+	EndTime    time.Time         `json:"endTime"`
+	Rounds     []RoundResult     `json:"rounds,omitempty"`
+	Throughput float64           `json:"throughput,omitempty"` // 各轮写入速度的平均值, records/second
+	Latency    *LatencyResult    `json:"latency,omitempty"`    // 全部轮次每次写入请求的耗时
+	QueueDelay *LatencyResult    `json:"queueDelay,omitempty"` // 开环写入时全部轮次的排队时间, 此时 Latency 从计划发送时间算起
+	// OnError 为 retry 时全部轮次重试过的请求从第一次失败到最终成功或放弃的时间
+	RetryLatency *LatencyResult    `json:"retryLatency,omitempty"`
+	Queries      []QueryResult     `json:"queries,omitempty"`
+	Saturation   *SaturationResult `json:"saturation,omitempty"` // 饱和点搜索的结果, Throughput 与 Latency 为拐点处的值
+	// 指定 -check 时最后一轮写入后查询测试读取的记录的聚合值, 用于校验之后的查询结果
+	Expected *Aggregates `json:"expected,omitempty"`
+	Errors   []string    `json:"errors,omitempty"`
+}
+
+// RoundResult 一轮写入的结果
+type RoundResult struct {
+	Round      int            `json:"round"`
+	StartTime  time.Time      `json:"startTime"`
+	EndTime    time.Time      `json:"endTime"`
+	Records    int            `json:"records"`
+	Seconds    float64        `json:"seconds"`
+	Throughput float64        `json:"throughput"`           // records/second
+	TargetRate float64        `json:"targetRate,omitempty"` // 限速写入时的目标速度
+	FailedRows int            `json:"failedRows,omitempty"` // 写入失败的记录数, Records 与 Throughput 只计写入成功的记录
+	Skipped    int            `json:"skipped,omitempty"`    // 数据库不能写入而跳过的全部列为 NULL 的记录数
+	Errors     int            `json:"errors,omitempty"`     // 失败的写入请求数
+	Retries    int            `json:"retries,omitempty"`
+	Retried    int            `json:"retried,omitempty"` // 重试过的写入请求数
+	Workers    []WorkerResult `json:"workers,omitempty"` // 有失败或重试时每个客户端的结果
+	Bottleneck string         `json:"bottleneck"`
+	Latency    *LatencyResult `json:"latency"`
+	QueueDelay *LatencyResult `json:"queueDelay,omitempty"`
+	// 重试过的请求从第一次失败到最终成功或放弃的时间
+	RetryLatency *LatencyResult   `json:"retryLatency,omitempty"`
+	Intervals    []IntervalResult `json:"intervals,omitempty"` // 按 Interval 划分的写入速度与耗时的时间序列
+	Verify       *VerifyResult    `json:"verify,omitempty"`    // 写入后的校验结果
+}
+
+// QueryResult 一项查询测试的结果
+type QueryResult struct {
+	Name       string         `json:"name"`
+	StartTime  time.Time      `json:"startTime"`
+	Clients    int            `json:"clients"`
+	Iterations int            `json:"iterations"` // 每个客户端测量的次数
+	Errors     int            `json:"errors"`
+	Seconds    float64        `json:"seconds"`       // 并发执行时为总耗时, 否则为平均耗时
+	QPS        float64        `json:"qps,omitempty"` // 并发执行时的实际 QPS
+	Value      float64        `json:"value"`         // count 值、聚合值或读取的行数
+	Latency    *LatencyResult `json:"latency"`
+	Check      *QueryCheck    `json:"check,omitempty"` // 与写入时计算的期望值的比较
+}
+
+// LatencyResult 耗时分布, 单位毫秒
+type LatencyResult struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"minMs"`
+	Mean   float64 `json:"meanMs"`
+	P50    float64 `json:"p50Ms"`
+	P90    float64 `json:"p90Ms"`
+	P95    float64 `json:"p95Ms"`
+	P99    float64 `json:"p99Ms"`
+	P999   float64 `json:"p999Ms"`
+	Max    float64 `json:"maxMs"`
+	Stddev float64 `json:"stddevMs"`
+}
+
+func NewLatencyResult(s LatencySummary) *LatencyResult {
+	ms := func(d time.Duration) float64 {
+		return float64(d.Microseconds()) / 1000
+	}
+	return &LatencyResult{
+		Count:  s.Count,
+		Min:    ms(s.Min),
+		Mean:   ms(s.Mean),
+		P50:    ms(s.Median),
+		P90:    ms(s.P90),
+		P95:    ms(s.P95),
+		P99:    ms(s.P99),
+		P999:   ms(s.P999),
+		Max:    ms(s.Max),
+		Stddev: ms(s.Stddev),
+	}
+}
+
+func newResult(command string, b Backend) *Result {
+	return &Result{Command: command, Target: b.Name(), StartTime: time.Now()}
+}
+
+// setVersion 在连接数据库后查询服务端版本
+func (r *Result) setVersion(b Backend) {
+	if vb, ok := b.(VersionBackend); ok {
+		r.Version = vb.Version()
+	}
+}
+
+// finish 记录结束时间与错误, 返回 err
+func (r *Result) finish(err error) (error, *Result) {
+	r.EndTime = time.Now()
+	if err != nil {
+		r.Errors = append(r.Errors, err.Error())
+	}
+	return err, r
+}
+
+// failedRows 全部轮次写入失败的记录数
+func (r *Result) failedRows() int {
+	var failed int
+	for _, round := range r.Rounds {
+		failed += round.FailedRows
+	}
+	return failed
+}
+
+// retries 全部轮次写入请求的重试次数
+func (r *Result) retries() int {
+	var retries int
+	for _, round := range r.Rounds {
+		retries += round.Retries
+	}
+	return retries
+}
+
+// WriteJSON 将结果写入 json 文件
+func (r *Result) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		fmt.Printf("encode result fail, err:%v\n", err)
+		return err
+	}
+	if err = os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		fmt.Printf("write result file %s fail, err:%v\n", path, err)
+		return err
+	}
+	fmt.Printf("result has written to %s\n", path)
+	return nil
+}
+
+// WriteCSV 将结果写入 csv 文件, 每轮写入、全部轮次汇总(name 为 all)和每项查询各一行, 开环写入时每轮和汇总另有一行排队时间,
+// 指定 Interval 时每个区间一行, 参数展开为 param_<name> 列
+func (r *Result) WriteCSV(path string) error {
+	return writeResultsCSV(path, []*Result{r})
+}
+
+// writeResultsCSV 将多个结果写入同一个 csv 文件, 参数列为全部结果参数的并集
+func writeResultsCSV(path string, results []*Result) error {
+	names := map[string]bool{}
+	var params []string
+	for _, r := range results {
+		for name := range r.Params {
+			if !names[name] {
+				names[name] = true
+				params = append(params, name)
+			}
+		}
+	}
+	sort.Strings(params)
+
+	header := []string{"command", "target", "version", "label", "phase", "start_time", "end_time"}
+	for _, name := range params {
+		header = append(header, "param_"+name)
+	}
+	header = append(header, "kind", "name", "clients", "records", "seconds", "throughput", "qps", "value", "errors",
+		"count", "min_ms", "mean_ms", "p50_ms", "p90_ms", "p95_ms", "p99_ms", "p999_ms", "max_ms", "stddev_ms")
+	records := [][]string{header}
+	for _, r := range results {
+		records = append(records, r.csvRows(params)...)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("create result file %s fail, err:%v\n", path, err)
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err = w.WriteAll(records); err != nil {
+		fmt.Printf("write result file %s fail, err:%v\n", path, err)
+		return err
+	}
+	fmt.Printf("result has written to %s\n", path)
+	return nil
+}
+
+// csvRows 结果在 csv 中的各行, params 为参数列
+func (r *Result) csvRows(params []string) [][]string {
+	prefix := []string{r.Command, r.Target, r.Version, r.Label, r.Phase, r.StartTime.Format(time.RFC3339), r.EndTime.Format(time.RFC3339)}
+	for _, name := range params {
+		prefix = append(prefix, r.Params[name])
+	}
+	ftoa := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	row := func(kind, name string, clients, records int, seconds, throughput, qps, value float64, errors int, l *LatencyResult) []string {
+		record := append(append([]string{}, prefix...), kind, name, strconv.Itoa(clients), strconv.Itoa(records),
+			ftoa(seconds), ftoa(throughput), ftoa(qps), ftoa(value), strconv.Itoa(errors))
+		if l == nil {
+			l = &LatencyResult{}
+		}
+		return append(record, strconv.Itoa(l.Count), ftoa(l.Min), ftoa(l.Mean), ftoa(l.P50), ftoa(l.P90), ftoa(l.P95),
+			ftoa(l.P99), ftoa(l.P999), ftoa(l.Max), ftoa(l.Stddev))
+	}
+
+	var rows [][]string
+	var sumRecords int
+	var sumSeconds float64
+	for _, round := range r.Rounds {
+		rows = append(rows, row("round", strconv.Itoa(round.Round), 0, round.Records, round.Seconds, round.Throughput, 0, 0, round.Errors, round.Latency))
+		if round.QueueDelay != nil {
+			rows = append(rows, row("queue_delay", strconv.Itoa(round.Round), 0, 0, 0, 0, 0, 0, 0, round.QueueDelay))
+		}
+		if round.RetryLatency != nil {
+			// records 为重试过的请求数, value 为重试次数
+			rows = append(rows, row("retry", strconv.Itoa(round.Round), 0, round.Retried, 0, 0, 0, float64(round.Retries), 0, round.RetryLatency))
+		}
+		if round.Verify != nil {
+			// name 为 轮次:表名, records 为实际记录数, value 为期望记录数, errors 为之和不一致的列数
+			for _, t := range round.Verify.Tables {
+				rows = append(rows, row("verify", fmt.Sprintf("%d:%s", round.Round, t.Table), 0, int(t.Actual), 0, 0, 0, float64(t.Expected), len(t.Mismatch), nil))
+			}
+		}
+		for _, in := range round.Intervals {
+			// name 为 轮次:区间结束时距本轮开始的秒数
+			name := fmt.Sprintf("%d:%s", round.Round, ftoa(math.Round(in.Elapsed*10)/10))
+			rows = append(rows, row("interval", name, 0, in.Records, in.Seconds, in.Throughput, 0, 0, 0, in.Latency))
+		}
+		sumRecords += round.Records
+		sumSeconds += round.Seconds
+	}
+	if r.Command == "write" || r.Command == "mixed" {
+		rows = append(rows, row("run", "all", 0, sumRecords, sumSeconds, r.Throughput, 0, 0, len(r.Errors), r.Latency))
+		if r.QueueDelay != nil {
+			rows = append(rows, row("queue_delay", "all", 0, 0, 0, 0, 0, 0, 0, r.QueueDelay))
+		}
+		if r.RetryLatency != nil {
+			rows = append(rows, row("retry", "all", 0, r.RetryLatency.Count, 0, 0, 0, float64(r.retries()), 0, r.RetryLatency))
+		}
+	}
+	if r.Saturation != nil {
+		// value 为拐点的目标速度, throughput 与耗时为拐点处的实际值
+		rows = append(rows, row("knee", r.Saturation.Search, 0, 0, 0, r.Throughput, 0, r.Saturation.Knee, len(r.Errors), r.Latency))
+	}
+	for _, q := range r.Queries {
+		rows = append(rows, row("query", q.Name, q.Clients, 0, q.Seconds, 0, q.QPS, q.Value, q.Errors, q.Latency))
+	}
+	return rows
+}
+
+// Report 一个测试场景中全部测试的合并结果
+type Report struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Results   []*Result `json:"results"`
+	Series    []Series  `json:"series,omitempty"` // 参数扫描时写入速度与耗时随参数变化的曲线
+	Errors    []string  `json:"errors,omitempty"`
+}
+
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		fmt.Printf("encode report fail, err:%v\n", err)
+		return err
+	}
+	if err = os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		fmt.Printf("write report file %s fail, err:%v\n", path, err)
+		return err
+	}
+	fmt.Printf("report has written to %s\n", path)
+	return nil
+}
+
+// WriteCSV 将全部结果写入同一个 csv 文件
+func (r *Report) WriteCSV(path string) error {
+	return writeResultsCSV(path, r.Results)
+}
+
+// Print 输出每个测试的主要指标: 写入速度与请求耗时, 各项查询的平均耗时与 p99
+func (r *Report) Print() {
+	fmt.Printf("======== report of %s: %d tests, %s ~ %s ========\n", r.Name, len(r.Results),
+		r.StartTime.Format(TsLayout), r.EndTime.Format(TsLayout))
+	for _, res := range r.Results {
+		fmt.Printf("[%s] %s %s version:%s\n", res.Phase, res.Target, res.Command, res.Version)
+		if (res.Command == "write" || res.Command == "mixed") && res.Latency != nil {
+			fmt.Printf("  %-30s %f records/second, batch p50 %.3fms p99 %.3fms\n", "write", res.Throughput, res.Latency.P50, res.Latency.P99)
+		}
+		if res.QueueDelay != nil {
+			fmt.Printf("  %-30s p50 %.3fms p99 %.3fms\n", "queue delay", res.QueueDelay.P50, res.QueueDelay.P99)
+		}
+		if res.RetryLatency != nil && res.RetryLatency.Count > 0 {
+			fmt.Printf("  %-30s %d retries in %d requests, p50 %.3fms p99 %.3fms\n", "write retry", res.retries(), res.RetryLatency.Count,
+				res.RetryLatency.P50, res.RetryLatency.P99)
+		}
+		if res.Saturation != nil {
+			fmt.Printf("  %-30s %.0f records/second (%s search, p99 slo %.3fms, %d steps)\n", "knee point", res.Saturation.Knee,
+				res.Saturation.Search, res.Saturation.SLO, len(res.Saturation.Steps))
+		}
+		for _, q := range res.Queries {
+			if q.Latency == nil || q.Latency.Count == 0 {
+				fmt.Printf("  %-30s failed\n", q.Name)
+				continue
+			}
+			fmt.Printf("  %-30s mean %.3fms p99 %.3fms\n", q.Name, q.Latency.Mean, q.Latency.P99)
+		}
+		for _, e := range res.Errors {
+			fmt.Printf("  error: %s\n", e)
+		}
+	}
+	PrintCorrectness(r.Results)
+	for _, e := range r.Errors {
+		fmt.Printf("error: %s\n", e)
+	}
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy 写入请求失败后的重试策略: 第 i 次重试前等待 min(BaseDelay*2^(i-1), MaxDelay),
+// 再按 Jitter 随机缩短, 避免多个客户端同时重试
+type RetryPolicy struct {
+	MaxRetries int           // 每个请求最多重试的次数
+	BaseDelay  time.Duration // 第一次重试前的等待时间
+	MaxDelay   time.Duration // 等待时间的上限
+	Jitter     float64       // 0~1, 等待时间在 [d*(1-Jitter), d] 内均匀分布
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second, Jitter: 0.5}
+
+func (r RetryPolicy) check() error {
+	if r.MaxRetries <= 0 || r.BaseDelay < 0 || r.MaxDelay < r.BaseDelay || r.Jitter < 0 || r.Jitter > 1 {
+		return errors.New("invalid retry policy, required writeRetries > 0, 0 <= retryDelay <= retryMaxDelay and 0 <= retryJitter <= 1")
+	}
+	return nil
+}
+
+// Delay 第 retry 次(从 1 开始)重试前的等待时间
+func (r RetryPolicy) Delay(retry int, rng *rand.Rand) time.Duration {
+	d := r.BaseDelay
+	for i := 1; i < retry && d < r.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > r.MaxDelay {
+		d = r.MaxDelay
+	}
+	if r.Jitter > 0 && rng != nil {
+		d -= time.Duration(rng.Float64() * r.Jitter * float64(d))
+	}
+	return d
+}
+
+// RetryableBackend 由能识别自身临时性错误的数据库实现, 如事务冲突、写入过载;
+// 未实现时只重试 IsTransientError 识别的网络错误
+type RetryableBackend interface {
+	Retryable(err error) bool
+}
+
+// isRetryable 判断 b 返回的写入错误是否可以重试
+func isRetryable(b Backend, err error) bool {
+	if rb, ok := b.(RetryableBackend); ok {
+		return rb.Retryable(err)
+	}
+	return IsTransientError(err)
+}
+
+// IsTransientError 判断是否为连接断开、超时等网络错误, 重新发送请求可能成功。
+// 地址无法解析等其他网络错误重试也不会成功, 不在其中
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errorContains(err, "connection reset", "connection refused", "broken pipe", "i/o timeout", "invalid connection", "bad connection")
+}
+
+// errorContains 判断错误信息是否包含 substrs 之一, 不区分大小写
+func errorContains(err error, substrs ...string) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range substrs {
+		if strings.Contains(msg, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package common
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, d := range want {
+		if got := policy.Delay(i+1, nil); got != d {
+			t.Errorf("Delay(%d) = %v, want %v", i+1, got, d)
+		}
+	}
+	// 重试次数很大时不溢出
+	if got := policy.Delay(1000, nil); got != time.Second {
+		t.Errorf("Delay(1000) = %v, want %v", got, time.Second)
+	}
+
+	zero := RetryPolicy{MaxRetries: 3}
+	if got := zero.Delay(3, nil); got != 0 {
+		t.Errorf("Delay with zero base delay = %v, want 0", got)
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		jitter float64
+		retry  int
+		max    time.Duration
+	}{
+		{0, 1, 100 * time.Millisecond},
+		{0.5, 1, 100 * time.Millisecond},
+		{0.5, 3, 400 * time.Millisecond},
+		{1, 2, 200 * time.Millisecond},
+		{0.5, 10, time.Second},
+	}
+	for _, tt := range tests {
+		policy := RetryPolicy{MaxRetries: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: tt.jitter}
+		min := tt.max - time.Duration(tt.jitter*float64(tt.max))
+		for i := 0; i < 100; i++ {
+			if got := policy.Delay(tt.retry, rng); got < min || got > tt.max {
+				t.Fatalf("jitter %v: Delay(%d) = %v, want within [%v, %v]", tt.jitter, tt.retry, got, min, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyCheck(t *testing.T) {
+	tests := []struct {
+		policy RetryPolicy
+		valid  bool
+	}{
+		{DefaultRetryPolicy, true},
+		{RetryPolicy{MaxRetries: 1}, true},
+		{RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Second, Jitter: 1}, true},
+		{RetryPolicy{MaxRetries: 0, BaseDelay: time.Second, MaxDelay: time.Second}, false},
+		{RetryPolicy{MaxRetries: 1, BaseDelay: -time.Second, MaxDelay: time.Second}, false},
+		{RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Millisecond}, false},
+		{RetryPolicy{MaxRetries: 1, Jitter: -0.1}, false},
+		{RetryPolicy{MaxRetries: 1, Jitter: 1.5}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.check(); (err == nil) != tt.valid {
+			t.Errorf("check(%+v) = %v, want valid %v", tt.policy, err, tt.valid)
+		}
+	}
+}
+
+// timeoutError 实现 net.Error 的超时错误
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "deadline reached" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{nil, false},
+		{driver.ErrBadConn, true},
+		{io.EOF, true},
+		{fmt.Errorf("read packet: %w", io.ErrUnexpectedEOF), true},
+		{context.DeadlineExceeded, true},
+		{&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{&net.OpError{Op: "write", Net: "tcp", Err: syscall.EPIPE}, true},
+		{&net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, true},
+		{errors.New("Post http://127.0.0.1:8086/write: i/o timeout"), true},
+		{errors.New("invalid connection"), true},
+		// 地址错误等重试也不会成功的网络错误
+		{&net.DNSError{Err: "no such host", Name: "wrong-host"}, false},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "wrong-host"}}, false},
+		{&net.AddrError{Err: "missing port in address", Addr: "127.0.0.1"}, false},
+		{errors.New("syntax error at position 10"), false},
+	}
+	for _, tt := range tests {
+		if got := IsTransientError(tt.err); got != tt.transient {
+			t.Errorf("IsTransientError(%v) = %v, want %v", tt.err, got, tt.transient)
+		}
+	}
+}
+
+// retryableBackend 只有 "busy" 错误可以重试
+type retryableBackend struct{ Backend }
+
+func (retryableBackend) Retryable(err error) bool { return errorContains(err, "busy") }
+
+func TestIsRetryable(t *testing.T) {
+	var plain Backend
+	custom := retryableBackend{}
+	tests := []struct {
+		b     Backend
+		err   error
+		retry bool
+	}{
+		{plain, io.EOF, true},
+		{plain, errors.New("server is BUSY"), false},
+		{custom, errors.New("server is BUSY"), true},
+		// 实现 RetryableBackend 时由数据库决定, 不再按网络错误判断
+		{custom, io.EOF, false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.b, tt.err); got != tt.retry {
+			t.Errorf("isRetryable(%T, %v) = %v, want %v", tt.b, tt.err, got, tt.retry)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 饱和点的搜索方式
+const (
+	SearchStep   = "step"   // 从起始速度按固定步长递增, 直到不可持续
+	SearchBinary = "binary" // 在可持续与不可持续的速度之间二分查找
+)
+
+const (
+	DefaultSLO       = 100 * time.Millisecond
+	DefaultPrecision = 0.05
+	// 实际写入速度达到目标速度的该比例才算跟上了目标速度
+	sustainRatio = 0.95
+	// binary 未指定最大速度时倍增查找上界的最大次数
+	maxDoublings = 20
+	// step 未指定最大速度时最多测试的速度数
+	maxSteps = 100
+)
+
+// SaturationParams 饱和点搜索参数, 每个测试的速度写入一轮 WriteParams 指定的数据
+type SaturationParams struct {
+	SLO       time.Duration // 每次写入请求耗时 p99 的上限
+	Search    string        // step|binary
+	Start     float64       // 起始速度, records/second
+	Step      float64       // step 每次增加的速度, 0 表示与 Start 相同
+	Max       float64       // 最大速度, 0 表示不限制; step 不限制时最多测试 maxSteps 个速度, binary 不限制时从 Start 起倍增直到不可持续
+	Precision float64       // binary 在区间宽度小于上界的该比例时停止
+}
+
+// SaturationResult 饱和点搜索的结果, 每个测试速度的详细结果见 Result.Rounds
+type SaturationResult struct {
+	Search string           `json:"search"`
+	SLO    float64          `json:"sloMs"`
+	Knee   float64          `json:"knee"` // 满足 SLO 的最大目标速度, 0 表示起始速度也不可持续
+	Steps  []SaturationStep `json:"steps"`
+}
+
+// SaturationStep 一个目标速度的测试结果
+type SaturationStep struct {
+	Rate        float64 `json:"rate"`       // 目标速度
+	Throughput  float64 `json:"throughput"` // 实际速度
+	P99         float64 `json:"p99Ms"`
+	Sustainable bool    `json:"sustainable"`
+	Reason      string  `json:"reason,omitempty"` // 不可持续的原因
+}
+
+func (s SaturationParams) check() error {
+	if s.Search != SearchStep && s.Search != SearchBinary {
+		err := errors.New(fmt.Sprintf("unrecognized search value:%s, required to be step|binary", s.Search))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	if s.SLO <= 0 || s.Start <= 0 || s.Step < 0 || s.Precision <= 0 || (s.Max > 0 && s.Max < s.Start) {
+		err := errors.New(fmt.Sprintf("invalid saturation args: slo=%v, rateStart=%g, rateStep=%g, rateMax=%g, precision=%g",
+			s.SLO, s.Start, s.Step, s.Max, s.Precision))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	return nil
+}
+
+// evaluate p99 不超过 SLO 且实际速度跟上目标速度时可持续。开环写入时跟不上的请求会排队, 排队时间已计入 p99,
+// 而泊松到达的实际速度本身有波动, 因此不检查实际速度
+func (s SaturationParams) evaluate(rate float64, round RoundResult, open bool, err error) SaturationStep {
+	step := SaturationStep{Rate: rate, Throughput: round.Throughput}
+	if round.Latency != nil {
+		step.P99 = round.Latency.P99
+	}
+	slo := float64(s.SLO.Microseconds()) / 1000
+	switch {
+	case err != nil:
+		step.Reason = err.Error()
+	case step.P99 > slo:
+		step.Reason = fmt.Sprintf("p99 %.3fms exceeds slo %.3fms", step.P99, slo)
+	case !open && step.Throughput < rate*sustainRatio:
+		step.Reason = fmt.Sprintf("throughput %.0f is below %.0f%% of the target rate", step.Throughput, sustainRatio*100)
+	default:
+		step.Sustainable = true
+	}
+	return step
+}
+
+// RunSaturation 以限速方式写入, 按 step 或 binary 搜索每次写入请求耗时 p99 不超过 SLO 且实际速度跟上目标速度的最大速度(拐点)。
+// 每个速度写入一轮, 之间清空表; 写入出错视为该速度不可持续, 清空表或 PreRound 失败时终止搜索。
+// Arrival 为 fixed 或 poisson 时以开环方式写入, p99 从计划发送时间算起, 包含排队时间。
+// 没有可持续的速度且有写入出错时返回第一个写入错误
+func RunSaturation(b Backend, p WriteParams, s SaturationParams) (error, *Result) {
+	result := newResult("saturate", b)
+	if s.Step == 0 {
+		s.Step = s.Start
+	}
+	if s.Precision == 0 {
+		s.Precision = DefaultPrecision
+	}
+	if err := s.check(); err != nil {
+		return result.finish(err)
+	}
+	p.Rate = s.Start
+	if err := prepareWrite(b, &p, p.T); err != nil {
+		return result.finish(err)
+	}
+	defer b.Close()
+	result.setVersion(b)
+	sat := &SaturationResult{Search: s.Search, SLO: float64(s.SLO.Microseconds()) / 1000}
+	result.Saturation = sat
+
+	knee := -1
+	var writeErr error
+	// try 以 rate 写入一轮, 返回是否可持续
+	try := func(rate float64) (error, bool) {
+		if err := beforeRound(b, p, len(sat.Steps)); err != nil {
+			return err, false
+		}
+		p.Rate = rate
+		err, round, _ := writeRound(b, p, len(sat.Steps))
+		if err != nil {
+			if writeErr == nil {
+				writeErr = err
+			}
+		}
+		step := s.evaluate(rate, round, p.Arrival == ArrivalFixed || p.Arrival == ArrivalPoisson, err)
+		sat.Steps = append(sat.Steps, step)
+		result.Rounds = append(result.Rounds, round)
+		if step.Sustainable {
+			fmt.Printf("rate %.0f records/second is sustainable\n", rate)
+			if rate > sat.Knee {
+				sat.Knee = rate
+				knee = len(result.Rounds) - 1
+			}
+		} else {
+			fmt.Printf("rate %.0f records/second is not sustainable: %s\n", rate, step.Reason)
+		}
+		return nil, step.Sustainable
+	}
+
+	var err error
+	if s.Search == SearchStep {
+		for i, rate := 0, s.Start; s.Max == 0 || rate <= s.Max; i, rate = i+1, rate+s.Step {
+			if s.Max == 0 && i == maxSteps {
+				fmt.Printf("rate %.0f records/second is still sustainable, stop searching\n", rate-s.Step)
+				break
+			}
+			var ok bool
+			if err, ok = try(rate); err != nil || !ok {
+				break
+			}
+		}
+	} else {
+		err = binarySearch(s, try)
+	}
+
+	PrintSaturation(b.Name(), sat)
+	if knee >= 0 {
+		// 拐点处的实际速度与耗时作为结果的写入速度与耗时, 便于 compare 对比
+		result.Throughput = result.Rounds[knee].Throughput
+		result.Latency = result.Rounds[knee].Latency
+	} else if err == nil {
+		// 没有可持续的速度且写入出错时, 多半是数据库不可用
+		err = writeErr
+	}
+	return result.finish(err)
+}
+
+// binarySearch 先确认起始速度可持续, 再确定不可持续的上界, 然后二分查找
+func binarySearch(s SaturationParams, try func(rate float64) (error, bool)) error {
+	err, ok := try(s.Start)
+	if err != nil || !ok {
+		return err
+	}
+	lo, hi := s.Start, s.Max
+	if hi > 0 {
+		if err, ok = try(hi); err != nil || ok {
+			return err
+		}
+	} else {
+		for i := 0; ; i++ {
+			if i == maxDoublings {
+				fmt.Printf("rate %.0f records/second is still sustainable, stop searching\n", lo)
+				return nil
+			}
+			rate := lo * 2
+			if err, ok = try(rate); err != nil {
+				return err
+			}
+			if !ok {
+				hi = rate
+				break
+			}
+			lo = rate
+		}
+	}
+
+	for hi-lo > hi*s.Precision {
+		mid := (lo + hi) / 2
+		if err, ok = try(mid); err != nil {
+			return err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return nil
+}
+
+// PrintSaturation 输出每个目标速度的测试结果与拐点
+func PrintSaturation(name string, sat *SaturationResult) {
+	fmt.Printf("======== %s saturation search (%s, p99 slo %.3fms) ========\n", name, sat.Search, sat.SLO)
+	fmt.Printf("%16s %16s %12s  %s\n", "target rate", "records/second", "p99(ms)", "sustainable")
+	for _, step := range sat.Steps {
+		state := "yes"
+		if !step.Sustainable {
+			state = "no, " + step.Reason
+		}
+		fmt.Printf("%16.0f %16.3f %12.3f  %s\n", step.Rate, step.Throughput, step.P99, state)
+	}
+	if sat.Knee > 0 {
+		fmt.Printf("======== %s knee point: %.0f records/second ========\n", name, sat.Knee)
+	} else {
+		fmt.Printf("======== %s has no sustainable rate ========\n", name)
+	}
+}
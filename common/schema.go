@@ -0,0 +1,331 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 列类型
+const (
+	TypeInt    = "int"
+	TypeBigint = "bigint"
+	TypeFloat  = "float"
+	TypeDouble = "double"
+	TypeBool   = "bool"
+	TypeString = "string"
+)
+
+const (
+	DefaultStringLength = 64
+	DefaultNullRate     = 0.1
+)
+
+// 各数据库的列类型, string 类型中的 %d 为长度
+var columnTypes = map[string]map[string]string{
+	MO:       {TypeInt: "INT", TypeBigint: "BIGINT", TypeFloat: "FLOAT", TypeDouble: "DOUBLE", TypeBool: "BOOL", TypeString: "VARCHAR(%d)"},
+	CK:       {TypeInt: "Int32", TypeBigint: "Int64", TypeFloat: "Float32", TypeDouble: "Float64", TypeBool: "Bool", TypeString: "String"},
+	TDengine: {TypeInt: "INT", TypeBigint: "BIGINT", TypeFloat: "FLOAT", TypeDouble: "DOUBLE", TypeBool: "BOOL", TypeString: "VARCHAR(%d)"},
+	SR:       {TypeInt: "INT", TypeBigint: "BIGINT", TypeFloat: "FLOAT", TypeDouble: "DOUBLE", TypeBool: "BOOLEAN", TypeString: "VARCHAR(%d)"},
+}
+
+// 未指定取值模型时各类型的默认模型
+var defaultModels = map[string]string{
+	TypeInt:    "uniform:min=0,max=100",
+	TypeBigint: "uniform:min=0,max=100000",
+	TypeFloat:  "uniform:min=0,max=1",
+	TypeDouble: "uniform:min=0,max=1",
+	TypeBool:   "uniform:min=0,max=1",
+	TypeString: "uniform:min=0,max=100",
+}
+
+// Column 表中的一列(ts 列除外), Gen 为取值模型, 格式同 ParseModelSpec
+type Column struct {
+	Name     string  `yaml:"name"`
+	Type     string  `yaml:"type"`
+	Length   int     `yaml:"length"` // string 类型的长度
+	Gen      string  `yaml:"gen"`
+	Nullable bool    `yaml:"nullable"`
+	NullRate float64 `yaml:"nullRate"` // 可为空的列生成 NULL 的比例, 默认 0.1
+
+	Model ModelSpec `yaml:"-"`
+}
+
+// Schema 测试表结构: ts 列之后的数据列, 以及可选的标签列。
+// 标签列在每张表内取值固定, TDengine 中为超级表的 TAGS, InfluxDB 中为 tag, 其他数据库中为普通列
+//
+// Devices 大于 0 时按设备(时间线)组织数据, 各数据库使用各自惯用的布局:
+// TDengine 每个设备一张子表, InfluxDB 以 device_id 作为 tag, 其他数据库写一张带 device_id 列的宽表。
+// 此时标签列按设备取值, 写入模式 multi|single 不再生效
+type Schema struct {
+	Columns []Column `yaml:"columns"`
+	Tags    []Column `yaml:"tags"`
+	Devices int      `yaml:"devices"`
+}
+
+// DefaultSchema 默认表结构 (ts, current, voltage, phase), 无标签列
+func DefaultSchema() *Schema {
+	s := &Schema{
+		Columns: []Column{
+			{Name: "current", Type: TypeFloat, Gen: "uniform:min=-3,max=3"},
+			{Name: "voltage", Type: TypeInt, Gen: "uniform:min=0,max=20"},
+			{Name: "phase", Type: TypeFloat, Gen: "uniform:min=-1,max=0"},
+		},
+	}
+	s.init()
+	return s
+}
+
+// LoadSchema 从 yaml 文件读取表结构, 如:
+//
+//	columns:
+//	  - {name: current, type: float, gen: "sine:amp=3,period=1440,noise=0.1"}
+//	  - {name: status, type: string, length: 16, nullable: true}
+//	tags:
+//	  - {name: location, type: string, gen: "uniform:min=0,max=50"}
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("read schema file fail, err:%v\n", err)
+		return nil, err
+	}
+	s := &Schema{}
+	if err = yaml.Unmarshal(data, s); err != nil {
+		fmt.Printf("parse schema file %s fail, err:%v\n", path, err)
+		return nil, err
+	}
+	if err = s.init(); err != nil {
+		fmt.Printf("%v\n", err)
+		return nil, err
+	}
+	return s, nil
+}
+
+// init 校验列定义, 补齐默认值并解析取值模型
+func (s *Schema) init() error {
+	if len(s.Columns) == 0 {
+		return errors.New("schema has no columns")
+	}
+	if s.Devices < 0 {
+		return errors.New(fmt.Sprintf("invalid devices:%d", s.Devices))
+	}
+	names := map[string]bool{"ts": true, DeviceColumn: true}
+	for _, cols := range [][]Column{s.Columns, s.Tags} {
+		for i := range cols {
+			c := &cols[i]
+			if c.Name == "" || names[strings.ToLower(c.Name)] {
+				return errors.New(fmt.Sprintf("invalid or duplicate column name:'%s'", c.Name))
+			}
+			names[strings.ToLower(c.Name)] = true
+			if _, ok := defaultModels[c.Type]; !ok {
+				return errors.New(fmt.Sprintf("unrecognized type %s of column %s, required to be int|bigint|float|double|bool|string", c.Type, c.Name))
+			}
+			if c.Length <= 0 {
+				c.Length = DefaultStringLength
+			}
+			if c.Nullable && c.NullRate <= 0 {
+				c.NullRate = DefaultNullRate
+			}
+			if c.Gen == "" {
+				c.Gen = defaultModels[c.Type]
+			}
+			m, err := ParseModelSpec(c.Gen)
+			if err != nil {
+				return errors.New(fmt.Sprintf("column %s: %v", c.Name, err))
+			}
+			c.Model = m
+		}
+	}
+	return nil
+}
+
+// SetModel 修改名为 name 的列(或标签列)的取值模型
+func (s *Schema) SetModel(name string, m ModelSpec) error {
+	for _, cols := range [][]Column{s.Columns, s.Tags} {
+		for i := range cols {
+			if cols[i].Name == name {
+				cols[i].Model = m
+				cols[i].Gen = m.String()
+				return nil
+			}
+		}
+	}
+	return errors.New(fmt.Sprintf("unrecognized column:%s", name))
+}
+
+// AllColumns 返回没有标签概念的数据库中 ts 之后的全部列: 按设备写入时的 device_id 列、数据列、标签列
+func (s *Schema) AllColumns() []Column {
+	var cols []Column
+	if s.Devices > 0 {
+		cols = append(cols, Column{Name: DeviceColumn, Type: TypeInt})
+	}
+	return append(append(cols, s.Columns...), s.Tags...)
+}
+
+// Row 返回记录在 AllColumns 各列上的值, tags 为未按设备写入时所写表的标签值
+func (s *Schema) Row(rec Record, tags []interface{}) []interface{} {
+	row := make([]interface{}, 0, len(rec.Values)+len(s.Tags)+1)
+	if s.Devices > 0 {
+		row = append(row, int64(rec.Device))
+		tags = rec.Tags
+	}
+	return append(append(row, rec.Values...), tags...)
+}
+
+// ColumnNames 以逗号分隔的 ts 及全部列名, 列名用反引号括起
+func (s *Schema) ColumnNames() string {
+	names := []string{"`ts`"}
+	for _, c := range s.AllColumns() {
+		names = append(names, "`"+c.Name+"`")
+	}
+	return strings.Join(names, ",")
+}
+
+// DeviceRange 返回第 worker 个客户端(共 T1 个)负责的设备 [first, first+count), 设备按客户端连续均分
+func (s *Schema) DeviceRange(worker, T1 int) (first, count int) {
+	return splitRange(s.Devices, worker, T1)
+}
+
+// splitRange 将 total 个对象连续均分给 T1 个客户端, 返回第 worker 个客户端的 [first, first+count)
+func splitRange(total, worker, T1 int) (first, count int) {
+	first = worker * total / T1
+	return first, (worker+1)*total/T1 - first
+}
+
+// AggColumn 聚合、时间窗口查询使用的列: 第一个数值类型的数据列, 没有时返回空
+func (s *Schema) AggColumn() string {
+	for _, c := range s.Columns {
+		if c.IsNumeric() {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+func (s *Schema) String() string {
+	var cols []string
+	for _, c := range s.Columns {
+		cols = append(cols, c.String())
+	}
+	str := "columns(" + strings.Join(cols, ", ") + ")"
+	if s.Devices > 0 {
+		str += fmt.Sprintf(" devices(%d)", s.Devices)
+	}
+	if len(s.Tags) > 0 {
+		var tags []string
+		for _, c := range s.Tags {
+			tags = append(tags, c.String())
+		}
+		str += " tags(" + strings.Join(tags, ", ") + ")"
+	}
+	return str
+}
+
+func (c Column) String() string {
+	str := c.Name + " " + c.Type
+	if c.Nullable {
+		str += fmt.Sprintf(" null(%.2f)", c.NullRate)
+	}
+	return str + " " + c.Model.String()
+}
+
+func (c Column) IsNumeric() bool {
+	return c.Type == TypeInt || c.Type == TypeBigint || c.Type == TypeFloat || c.Type == TypeDouble
+}
+
+// SqlType 返回列在数据库 db 中的类型
+func (c Column) SqlType(db string) string {
+	t := columnTypes[db][c.Type]
+	if c.Type == TypeString && strings.Contains(t, "%d") {
+		t = fmt.Sprintf(t, c.Length)
+	}
+	return t
+}
+
+// ColumnDef 返回列在数据库 db 中的建表定义, 如 `current` FLOAT NOT NULL
+func (c Column) ColumnDef(db string) string {
+	t := c.SqlType(db)
+	switch db {
+	case CK:
+		if c.Nullable {
+			t = "Nullable(" + t + ")"
+		}
+		return "`" + c.Name + "` " + t
+	case TDengine:
+		// TDengine 的列均可为空
+		return "`" + c.Name + "` " + t
+	default:
+		if c.Nullable {
+			return "`" + c.Name + "` " + t + " NULL"
+		}
+		return "`" + c.Name + "` " + t + " NOT NULL"
+	}
+}
+
+// ColumnDefs 以逗号分隔的 cols 在数据库 db 中的建表定义
+func ColumnDefs(db string, cols []Column) string {
+	var defs []string
+	for _, c := range cols {
+		defs = append(defs, c.ColumnDef(db))
+	}
+	return strings.Join(defs, ", ")
+}
+
+// Convert 将取值模型生成的值转换为列类型对应的 Go 类型: int64、float64、bool、string
+func (c Column) Convert(v float64) interface{} {
+	switch c.Type {
+	case TypeInt:
+		return int64(math.Max(math.MinInt32, math.Min(math.MaxInt32, math.Floor(v))))
+	case TypeBigint:
+		return int64(math.Floor(v))
+	case TypeFloat:
+		// float 列保留 7 位小数
+		return math.Round(v*1e7) / 1e7
+	case TypeBool:
+		return v >= 0.5
+	case TypeString:
+		s := c.Name + "_" + strconv.FormatInt(int64(math.Floor(v)), 10)
+		if len(s) > c.Length {
+			s = s[:c.Length]
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// FormatValue 将值格式化为文本, NULL 返回 null 参数
+func FormatValue(v interface{}, null string) string {
+	switch x := v.(type) {
+	case nil:
+		return null
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case string:
+		return x
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// SqlValue 将值格式化为 sql 字面量, 字符串加单引号
+func SqlValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return FormatValue(v, "NULL")
+}
+
+// CsvValue 将值格式化为 csv 字段, NULL 为 \N
+func CsvValue(v interface{}) string {
+	return FormatValue(v, `\N`)
+}
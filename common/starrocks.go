@@ -0,0 +1,165 @@
+package common
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+// SRBackend StarRocks 写入与查询, 写入通过 Stream Load 导入 csv 数据, 所有客户端写同一张表
+type SRBackend struct {
+	conf       *SRConfig
+	schema     *Schema
+	dbList     []*sql.DB
+	loader     *StreamLoadClient
+	loadedRows int64 // Stream Load 返回的导入行数, 由 LoadedRows 读取后清零
+}
+
+func NewSRBackend(conf *SRConfig, schema *Schema) *SRBackend {
+	return &SRBackend{
+		conf:   conf,
+		schema: schema,
+	}
+}
+
+func (s *SRBackend) Name() string {
+	return SR
+}
+
+func (s *SRBackend) Version() string {
+	_, version := QueryVersion(s.dbList[0], "select current_version()")
+	return version
+}
+
+// Retryable Stream Load 的 5xx 响应、版本过多、导入超时和网络错误可以重试。
+// Publish Timeout 时数据已提交, Load 按成功处理; label 已存在表示之前的导入已提交, 重新导入会产生重复数据, 不可重试
+func (s *SRBackend) Retryable(err error) bool {
+	if errorContains(err, "label already exists") {
+		return false
+	}
+	return IsTransientError(err) || errorContains(err, "http status:5", "too many versions", "timeout", "too many tablet versions")
+}
+
+func (s *SRBackend) tableName() string {
+	return s.conf.Database + "." + s.conf.Table
+}
+
+func (s *SRBackend) CheckArgs(p WriteParams) error {
+	return nil
+}
+
+func (s *SRBackend) Connect(T1 int) error {
+	url := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?loc=UTC&parseTime=true", s.conf.User, s.conf.Password, s.conf.Host, s.conf.JdbcPort, "")
+	fmt.Printf("url:%s\n", url)
+	err, dbList := GetDbConn(T1, url)
+	s.dbList = dbList
+	s.loader = NewStreamLoadClient(s.conf)
+	return err
+}
+
+// InitTable 导入数据前先初始化表，创建数据库、表，已创建的话，先清空表数据
+func (s *SRBackend) InitTable(T1 int) error {
+	db := s.dbList[0]
+	database := s.conf.Database
+	tableName := s.tableName()
+	var err error
+	ctDatabaseSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", database)
+	_, err = db.Exec(ctDatabaseSQL)
+	if err != nil {
+		fmt.Printf("create database  %s fail:%v \n", database, err)
+		return err
+	}
+
+	// 标签列作为普通列放在数据列之后, 按设备写入时按 device_id 分桶
+	distributedBy := "ts"
+	if s.schema.Devices > 0 {
+		distributedBy = DeviceColumn
+	}
+	ctTableSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s(ts DATETIME not null, %s) DISTRIBUTED BY HASH(`%s`) BUCKETS 1 PROPERTIES ( \"replication_num\" = \"1\");",
+		tableName, ColumnDefs(SR, s.schema.AllColumns()), distributedBy)
+	_, err = db.Exec(ctTableSQL)
+	if err != nil {
+		fmt.Printf("create table  %s fail:%v \n", tableName, err)
+		return err
+	}
+
+	if err = s.TruncateTables(T1); err != nil {
+		return err
+	}
+
+	fmt.Printf("Initialize data table completed.\n")
+	return nil
+}
+
+func (s *SRBackend) TruncateTables(T1 int) error {
+	tableName := s.tableName()
+	delSql := fmt.Sprintf("truncate table %s", tableName)
+	if err := ExecSql(s.dbList[0], delSql); err != nil {
+		fmt.Printf("truncate table %s fail:%v \n", tableName, err)
+		return err
+	}
+	return nil
+}
+
+// RecordTable 所有客户端写同一张表
+func (s *SRBackend) RecordTable(worker int, rec Record) string {
+	return s.tableName()
+}
+
+// QueryChecksum ts 列为秒级的 DATETIME, 毫秒部分不保证保留, 不校验 ts
+func (s *SRBackend) QueryChecksum(table string, sums bool) (error, TableChecksum) {
+	return QueryChecksum(s.dbList[0], table, "", s.schema.Columns, sums)
+}
+
+func (s *SRBackend) Queried(table string) bool {
+	return true
+}
+
+// EncodeBatch 将记录编码为 Stream Load 导入的 csv 数据
+func (s *SRBackend) EncodeBatch(worker int, records []Record) (error, interface{}) {
+	var buffer bytes.Buffer
+	// 所有客户端写同一张表, 标签取第 0 张表的值
+	tags := s.schema.TagValues(0)
+	for _, rec := range records {
+		buffer.WriteString(rec.TsString())
+		for _, v := range s.schema.Row(rec, tags) {
+			buffer.WriteString("," + CsvValue(v))
+		}
+		buffer.WriteString("\n")
+	}
+	return nil, buffer.Bytes()
+}
+
+func (s *SRBackend) WriteBatch(worker int, batch interface{}) error {
+	err, result := s.loader.Load(batch.([]byte))
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	atomic.AddInt64(&s.loadedRows, result.NumberLoadedRows)
+	return nil
+}
+
+// LoadedRows 返回上次调用以来 Stream Load 导入成功的行数
+func (s *SRBackend) LoadedRows() int64 {
+	return atomic.SwapInt64(&s.loadedRows, 0)
+}
+
+func (s *SRBackend) Flush(worker int) error {
+	return nil
+}
+
+func (s *SRBackend) Queries() []string {
+	return []string{QueryNameCount, QueryNameSelect, QueryNamePoint, QueryNameAvg, QueryNameSum, QueryNameMax, QueryNameMin, QueryNameLatest}
+}
+
+func (s *SRBackend) RunQuery(worker int, name string) (error, float64) {
+	return RunSqlQuery(s.dbList[worker], name, s.tableName(), s.conf.PointQueryTsCondition, s.schema.AggColumn(), "")
+}
+
+func (s *SRBackend) Close() {
+	for _, conn := range s.dbList {
+		conn.Close()
+	}
+}
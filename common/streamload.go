@@ -0,0 +1,102 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	StreamLoadSuccess = "Success"
+	// 数据已提交, 只是在超时前尚未全部生效, 之后会可见, 按导入成功处理
+	StreamLoadPublishTimeout = "Publish Timeout"
+)
+
+// StreamLoadResult Stream Load 返回的导入结果
+type StreamLoadResult struct {
+	TxnId              int64  `json:"TxnId"`
+	Label              string `json:"Label"`
+	Status             string `json:"Status"`
+	Message            string `json:"Message"`
+	NumberTotalRows    int64  `json:"NumberTotalRows"`
+	NumberLoadedRows   int64  `json:"NumberLoadedRows"`
+	NumberFilteredRows int64  `json:"NumberFilteredRows"`
+	ErrorURL           string `json:"ErrorURL"`
+}
+
+// StreamLoadClient StarRocks Stream Load 客户端, 请求发往 FE, 由 FE 重定向到 BE 导入
+type StreamLoadClient struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+}
+
+func NewStreamLoadClient(conf *SRConfig) *StreamLoadClient {
+	c := &StreamLoadClient{
+		url:      fmt.Sprintf("http://%s:%s/api/%s/%s/_stream_load", conf.Host, conf.HttpPort, conf.Database, conf.Table),
+		user:     conf.User,
+		password: conf.Password,
+	}
+	c.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ExpectContinueTimeout: 5 * time.Second,
+			MaxIdleConnsPerHost:   16,
+		},
+		// FE 返回 307 重定向到 BE, 重定向到其他主机时 net/http 会去掉 Authorization, 需要重新设置, 同 curl --location-trusted
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stream load: stopped after 10 redirects")
+			}
+			req.SetBasicAuth(c.user, c.password)
+			return nil
+		},
+	}
+	return c
+}
+
+// Load 以 csv 格式导入 data, Status 不为 Success 或 Publish Timeout 时返回错误
+func (c *StreamLoadClient) Load(data []byte) (error, *StreamLoadResult) {
+	// 请求体为 bytes.Reader, 重定向时可以重新发送
+	req, err := http.NewRequest(http.MethodPut, c.url, bytes.NewReader(data))
+	if err != nil {
+		return err, nil
+	}
+	req.SetBasicAuth(c.user, c.password)
+	req.Header.Set("Expect", "100-continue")
+	req.Header.Set("column_separator", ",")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err, nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("stream load fail, http status:%s, body:%s", resp.Status, body)), nil
+	}
+
+	result := &StreamLoadResult{}
+	if err = json.Unmarshal(body, result); err != nil {
+		return errors.New(fmt.Sprintf("stream load fail, parse result err:%v, body:%s", err, body)), nil
+	}
+	if result.Status == StreamLoadPublishTimeout {
+		fmt.Printf("stream load warning, label:%s, status:%s, %d rows committed but not yet visible, message:%s\n",
+			result.Label, result.Status, result.NumberLoadedRows, result.Message)
+		return nil, result
+	}
+	if result.Status != StreamLoadSuccess {
+		err = errors.New(fmt.Sprintf("stream load fail, label:%s, status:%s, message:%s, loaded rows:%d, filtered rows:%d, error url:%s",
+			result.Label, result.Status, result.Message, result.NumberLoadedRows, result.NumberFilteredRows, result.ErrorURL))
+		return err, result
+	}
+	return nil, result
+}
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamLoadStatus(t *testing.T) {
+	tests := []struct {
+		body   string
+		ok     bool
+		loaded int64
+	}{
+		{`{"Label":"l1","Status":"Success","NumberLoadedRows":100}`, true, 100},
+		// 数据已提交, 之后可见
+		{`{"Label":"l2","Status":"Publish Timeout","NumberLoadedRows":100}`, true, 100},
+		{`{"Label":"l3","Status":"Fail","Message":"too many filtered rows","NumberFilteredRows":100}`, false, 0},
+		{`{"Label":"l4","Status":"Label Already Exists"}`, false, 0},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.ReadAll(r.Body)
+			w.Write([]byte(tt.body))
+		}))
+		host, port, _ := strings.Cut(strings.TrimPrefix(srv.URL, "http://"), ":")
+		c := NewStreamLoadClient(&SRConfig{Host: host, HttpPort: port, Database: Database, Table: Table})
+		err, result := c.Load([]byte("1,2,3\n"))
+		srv.Close()
+		if (err == nil) != tt.ok || result == nil || result.NumberLoadedRows != tt.loaded {
+			t.Errorf("Load with response %s = %v, %+v, want ok %v and %d loaded rows", tt.body, err, result, tt.ok, tt.loaded)
+		}
+	}
+}
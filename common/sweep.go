@@ -0,0 +1,250 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 参数扫描方式
+const (
+	SweepCartesian  = "cartesian" // 全部参数取值的笛卡尔积
+	SweepOneAtATime = "oat"       // 每次只改变一个参数, 其他参数取默认值
+)
+
+const maxSweepValues = 1000
+
+// SweepParam 扫描的参数及其取值
+type SweepParam struct {
+	Name   string
+	Values []string
+}
+
+// ParseSweep 解析扫描参数, 参数之间以分号分隔, 如 r=1000,10000,100000;T=1..16:x2;txc=0..100:20
+func ParseSweep(spec string) (error, []SweepParam) {
+	var params []SweepParam
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			err := errors.New(fmt.Sprintf("invalid sweep '%s', required to be name=values", item))
+			fmt.Printf("%v\n", err)
+			return err, nil
+		}
+		err, values := ExpandSweepValues(strings.Split(kv[1], ","))
+		if err != nil {
+			return err, nil
+		}
+		params = append(params, SweepParam{Name: strings.TrimSpace(kv[0]), Values: values})
+	}
+	if len(params) == 0 {
+		err := errors.New(fmt.Sprintf("invalid sweep '%s', no parameters", spec))
+		fmt.Printf("%v\n", err)
+		return err, nil
+	}
+	return nil, params
+}
+
+// ExpandSweepValues 展开取值列表中的整数范围: start..end 步长为 1, start..end:step 按 step 递增, start..end:xN 按 N 倍递增
+func ExpandSweepValues(list []string) (error, []string) {
+	var values []string
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "..") {
+			values = append(values, v)
+			continue
+		}
+
+		err, expanded := expandRange(v)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return err, nil
+		}
+		values = append(values, expanded...)
+		if len(values) > maxSweepValues {
+			err = errors.New(fmt.Sprintf("sweep range '%s' has more than %d values", v, maxSweepValues))
+			fmt.Printf("%v\n", err)
+			return err, nil
+		}
+	}
+	if len(values) == 0 {
+		err := errors.New("sweep parameter has no values")
+		fmt.Printf("%v\n", err)
+		return err, nil
+	}
+	return nil, values
+}
+
+func expandRange(v string) (error, []string) {
+	invalid := errors.New(fmt.Sprintf("invalid sweep range '%s', required to be start..end, start..end:step or start..end:xN", v))
+	bounds, step, _ := strings.Cut(v, ":")
+	startStr, endStr, _ := strings.Cut(bounds, "..")
+	start, err1 := strconv.Atoi(strings.TrimSpace(startStr))
+	end, err2 := strconv.Atoi(strings.TrimSpace(endStr))
+	if err1 != nil || err2 != nil || end < start {
+		return invalid, nil
+	}
+
+	next := func(x int) int { return x + 1 }
+	if step != "" {
+		if factor, ok := strings.CutPrefix(step, "x"); ok {
+			n, err := strconv.Atoi(factor)
+			if err != nil || n < 2 || start <= 0 {
+				return invalid, nil
+			}
+			next = func(x int) int { return x * n }
+		} else {
+			n, err := strconv.Atoi(step)
+			if err != nil || n <= 0 {
+				return invalid, nil
+			}
+			next = func(x int) int { return x + n }
+		}
+	}
+
+	var values []string
+	for x := start; x <= end && len(values) <= maxSweepValues; x = next(x) {
+		values = append(values, strconv.Itoa(x))
+	}
+	return nil, values
+}
+
+// SweepPoints 返回要测试的参数组合, 组合中只包含扫描的参数, 没有扫描参数时返回一个空组合
+func SweepPoints(params []SweepParam, mode string) []map[string]string {
+	if mode == SweepOneAtATime && len(params) > 0 {
+		var points []map[string]string
+		for _, p := range params {
+			for _, v := range p.Values {
+				points = append(points, map[string]string{p.Name: v})
+			}
+		}
+		return points
+	}
+
+	points := []map[string]string{{}}
+	for _, p := range params {
+		var next []map[string]string
+		for _, point := range points {
+			for _, v := range p.Values {
+				np := map[string]string{p.Name: v}
+				for k, pv := range point {
+					np[k] = pv
+				}
+				next = append(next, np)
+			}
+		}
+		points = next
+	}
+	return points
+}
+
+// Series 写入速度与请求耗时随一个参数变化的曲线, Fixed 为曲线上其他扫描参数的取值
+type Series struct {
+	Param  string            `json:"param"`
+	Fixed  map[string]string `json:"fixed,omitempty"`
+	Points []SeriesPoint     `json:"points"`
+}
+
+type SeriesPoint struct {
+	Value      string  `json:"value"`
+	Throughput float64 `json:"throughput"` // records/second
+	P50        float64 `json:"p50Ms"`
+	P99        float64 `json:"p99Ms"`
+	Failed     bool    `json:"failed,omitempty"`
+}
+
+func newSeriesPoint(value string, r *Result) SeriesPoint {
+	p := SeriesPoint{Value: value}
+	if r == nil || len(r.Errors) > 0 {
+		p.Failed = true
+	}
+	if r != nil {
+		p.Throughput = r.Throughput
+		if r.Latency != nil {
+			p.P50 = r.Latency.P50
+			p.P99 = r.Latency.P99
+		}
+	}
+	return p
+}
+
+// SweepSeries 按扫描参数整理各参数组合的结果, results 与 points 一一对应, 失败且没有结果时为 nil。
+// 每个参数在其他扫描参数取值相同的组合上形成一条曲线
+func SweepSeries(params []SweepParam, points []map[string]string, results []*Result) []Series {
+	var series []Series
+	for _, p := range params {
+		index := map[string]int{}
+		for i, point := range points {
+			value, ok := point[p.Name]
+			if !ok {
+				continue
+			}
+			fixed := map[string]string{}
+			var keys []string
+			for k, v := range point {
+				if k != p.Name {
+					fixed[k] = v
+					keys = append(keys, k+"="+v)
+				}
+			}
+			sort.Strings(keys)
+			key := strings.Join(keys, ",")
+			j, ok := index[key]
+			if !ok {
+				j = len(series)
+				index[key] = j
+				series = append(series, Series{Param: p.Name, Fixed: fixed})
+			}
+			series[j].Points = append(series[j].Points, newSeriesPoint(value, results[i]))
+		}
+	}
+	return series
+}
+
+// PrintSweep 输出每个参数组合的写入速度与请求耗时, 以及每条曲线
+func PrintSweep(params []SweepParam, points []map[string]string, results []*Result, series []Series) {
+	fmt.Printf("======== sweep of %d points ========\n", len(points))
+	for _, p := range params {
+		fmt.Printf("%-12s", p.Name)
+	}
+	fmt.Printf("%20s %12s %12s\n", "records/second", "p50(ms)", "p99(ms)")
+	for i, point := range points {
+		for _, p := range params {
+			v, ok := point[p.Name]
+			if !ok {
+				v = "-"
+			}
+			fmt.Printf("%-12s", v)
+		}
+		sp := newSeriesPoint("", results[i])
+		failed := ""
+		if sp.Failed {
+			failed = "  FAILED"
+		}
+		fmt.Printf("%20.3f %12.3f %12.3f%s\n", sp.Throughput, sp.P50, sp.P99, failed)
+	}
+
+	for _, s := range series {
+		var fixed []string
+		for k, v := range s.Fixed {
+			fixed = append(fixed, k+"="+v)
+		}
+		sort.Strings(fixed)
+		fmt.Printf("series %s", s.Param)
+		if len(fixed) > 0 {
+			fmt.Printf(" (%s)", strings.Join(fixed, ", "))
+		}
+		fmt.Printf(":\n")
+		for _, p := range s.Points {
+			fmt.Printf("  %s=%s: %.3f records/second, p50 %.3fms, p99 %.3fms\n", s.Param, p.Value, p.Throughput, p.P50, p.P99)
+		}
+	}
+}
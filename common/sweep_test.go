@@ -0,0 +1,142 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandSweepValues(t *testing.T) {
+	tests := []struct {
+		list []string
+		want []string // nil 表示应当报错
+	}{
+		{[]string{"1000", " 10000 ", "100000"}, []string{"1000", "10000", "100000"}},
+		{[]string{"loadLine", "", "insert"}, []string{"loadLine", "insert"}},
+		{[]string{"1..4"}, []string{"1", "2", "3", "4"}},
+		{[]string{"3..3"}, []string{"3"}},
+		{[]string{"0..100:20"}, []string{"0", "20", "40", "60", "80", "100"}},
+		{[]string{"0..100:+30"}, []string{"0", "30", "60", "90"}},
+		{[]string{"1..16:x2"}, []string{"1", "2", "4", "8", "16"}},
+		{[]string{"1..100:x10"}, []string{"1", "10", "100"}},
+		{[]string{"3..20:x3"}, []string{"3", "9"}},
+		{[]string{"0", "1..16:x4", "100"}, []string{"0", "1", "4", "16", "100"}},
+		{[]string{"5..1"}, nil},
+		{[]string{"a..b"}, nil},
+		{[]string{"1.."}, nil},
+		{[]string{"1..10:0"}, nil},
+		{[]string{"1..10:-1"}, nil},
+		{[]string{"1..10:x1"}, nil},
+		{[]string{"0..10:x2"}, nil},
+		{[]string{"1..10:y2"}, nil},
+		{[]string{"1..2000"}, nil},
+		{[]string{"", " "}, nil},
+	}
+	for _, tt := range tests {
+		err, got := ExpandSweepValues(tt.list)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("ExpandSweepValues(%q) = %q, want error", tt.list, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandSweepValues(%q) = %q, %v, want %q", tt.list, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSweep(t *testing.T) {
+	err, params := ParseSweep(" r=1000,10000 ; T=1..8:x2;; wType=insert ")
+	want := []SweepParam{
+		{Name: "r", Values: []string{"1000", "10000"}},
+		{Name: "T", Values: []string{"1", "2", "4", "8"}},
+		{Name: "wType", Values: []string{"insert"}},
+	}
+	if err != nil || !reflect.DeepEqual(params, want) {
+		t.Errorf("ParseSweep = %+v, %v, want %+v", params, err, want)
+	}
+
+	for _, spec := range []string{"", ";", "r", "=1,2", "r=", "T=4..1"} {
+		if err, params := ParseSweep(spec); err == nil {
+			t.Errorf("ParseSweep(%q) = %+v, want error", spec, params)
+		}
+	}
+}
+
+// pointKeys 将参数组合格式化为 name=value 的有序列表, 便于比较
+func pointKeys(points []map[string]string, names ...string) []string {
+	var keys []string
+	for _, point := range points {
+		var kv []string
+		for _, name := range names {
+			if v, ok := point[name]; ok {
+				kv = append(kv, name+"="+v)
+			}
+		}
+		if len(kv) != len(point) {
+			kv = append(kv, "unexpected keys")
+		}
+		keys = append(keys, strings.Join(kv, ","))
+	}
+	return keys
+}
+
+func TestSweepPoints(t *testing.T) {
+	params := []SweepParam{
+		{Name: "r", Values: []string{"1000", "10000"}},
+		{Name: "T", Values: []string{"1", "2", "4"}},
+	}
+	tests := []struct {
+		mode   string
+		params []SweepParam
+		want   []string
+	}{
+		{SweepCartesian, params, []string{
+			"r=1000,T=1", "r=1000,T=2", "r=1000,T=4",
+			"r=10000,T=1", "r=10000,T=2", "r=10000,T=4",
+		}},
+		// 未识别的方式按笛卡尔积展开
+		{"", params[:1], []string{"r=1000", "r=10000"}},
+		{SweepOneAtATime, params, []string{"r=1000", "r=10000", "T=1", "T=2", "T=4"}},
+		// 没有扫描参数时为一个空组合
+		{SweepCartesian, nil, []string{""}},
+		{SweepOneAtATime, nil, []string{""}},
+	}
+	for _, tt := range tests {
+		got := pointKeys(SweepPoints(tt.params, tt.mode), "r", "T")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SweepPoints(%d params, %q) = %q, want %q", len(tt.params), tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestSweepSeries(t *testing.T) {
+	params := []SweepParam{
+		{Name: "r", Values: []string{"1000", "10000"}},
+		{Name: "T", Values: []string{"1", "2"}},
+	}
+	points := SweepPoints(params, SweepCartesian)
+	results := make([]*Result, len(points))
+	for i := range points {
+		results[i] = &Result{Throughput: float64(i + 1), Latency: &LatencyResult{P50: 1, P99: 2}}
+	}
+	// 失败的组合
+	results[3] = nil
+
+	series := SweepSeries(params, points, results)
+	if len(series) != 4 {
+		t.Fatalf("SweepSeries returned %d series, want 4", len(series))
+	}
+	// 第一条曲线为 T=1 时 r 的变化
+	s := series[0]
+	if s.Param != "r" || !reflect.DeepEqual(s.Fixed, map[string]string{"T": "1"}) || len(s.Points) != 2 ||
+		s.Points[0].Value != "1000" || s.Points[0].Throughput != 1 || s.Points[1].Value != "10000" || s.Points[1].Throughput != 3 {
+		t.Errorf("series[0] = %+v, want r over T=1", s)
+	}
+	s = series[3]
+	if s.Param != "T" || !reflect.DeepEqual(s.Fixed, map[string]string{"r": "10000"}) || len(s.Points) != 2 ||
+		s.Points[0].Failed || !s.Points[1].Failed {
+		t.Errorf("series[3] = %+v, want T over r=10000 with the last point failed", s)
+	}
+}
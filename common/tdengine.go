@@ -0,0 +1,565 @@
+package common
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	taosCommon "github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/param"
+)
+
+const meters = "meters"
+
+// 表结构中没有定义标签列时超级表的标签列, 第 z 张子表取值为 ("test", z)
+var tdDefaultTags = []Column{
+	{Name: "location", Type: TypeString, Length: 64},
+	{Name: "groupId", Type: TypeInt},
+}
+
+// tdNative TDengine 原生接口(af 包)的连接, 用于 stmt、line、telnet 写入, 需要以 '-tags taos' 编译
+type tdNative interface {
+	SelectDB(db string) error
+	// InsertLines InfluxDB 行协议无模式写入, 时间戳精度为毫秒
+	InsertLines(lines []string) error
+	// InsertTelnet OpenTSDB telnet 协议无模式写入
+	InsertTelnet(lines []string) error
+	// InsertStmt 参数绑定写入一批子表
+	InsertStmt(batch *tdStmtBatch) error
+	Close()
+}
+
+// tdStmtBatch 一次参数绑定写入: 同一条预编译语句, 每张子表按列绑定一组参数
+type tdStmtBatch struct {
+	sql         string
+	columnTypes *param.ColumnType
+	tables      []tdStmtTable
+}
+
+type tdStmtTable struct {
+	name    string
+	tags    *param.Param // 为 nil 时子表已存在, 不绑定标签
+	columns []*param.Param
+}
+
+// TDengineBackend TDengine 写入与查询, 使用 taosSql 驱动, 由调用方导入 github.com/taosdata/driver-go/v3/taosSql
+//
+// single 模式所有客户端写子表 d0; multi 模式 t 张子表连续均分给各客户端, 每张表写 n 条记录;
+// 按设备写入时每个设备一张子表。multi 模式和按设备写入时子表在写入时通过 USING meters TAGS 自动创建。
+// wType 为 stmt|line|telnet 时通过 af 原生接口写入, 建库、清表和查询仍使用 taosSql
+type TDengineBackend struct {
+	conf       *DBConfig
+	mode       string
+	t          int    // 多表写入时的表数量
+	wType      string // sql|stmt|line|telnet
+	schema     *Schema
+	dbList     []*sql.DB
+	nativeList []tdNative
+
+	tags    [][]interface{} // 各子表的标签值, 在 InitTable 中生成
+	tagText []string        // 各子表的标签值按写入方式编码后的文本
+	stmtSql string
+}
+
+func NewTDengineBackend(conf *DBConfig, mode string, t int, wType string, schema *Schema) *TDengineBackend {
+	return &TDengineBackend{
+		conf:   conf,
+		mode:   mode,
+		t:      t,
+		wType:  wType,
+		schema: schema,
+	}
+}
+
+func (d *TDengineBackend) Name() string {
+	return TDengine
+}
+
+func (d *TDengineBackend) Version() string {
+	_, version := QueryVersion(d.dbList[0], "select server_version()")
+	return version
+}
+
+// Retryable 连接中断、超时、vnode 切换 leader 或恢复中、写入缓存已满等错误可以重试
+func (d *TDengineBackend) Retryable(err error) bool {
+	return IsTransientError(err) || errorContains(err, "unable to establish connection", "conn is broken", "timeout", "leader",
+		"restoring", "out of memory", "memory is full", "server is busy", "vnode is closed", "db is not ready")
+}
+
+func (d *TDengineBackend) CheckArgs(p WriteParams) error {
+	if err := CheckMode(d.mode); err != nil {
+		return err
+	}
+	if d.wType != Sql && d.wType != Stmt && d.wType != Line && d.wType != Telnet {
+		err := errors.New(fmt.Sprintf("unrecognized wType value:%s, required to be sql|stmt|line|telnet, default sql", d.wType))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	if d.Tables() > 0 && d.t <= 0 {
+		err := errors.New(fmt.Sprintf("invalid t value:%d, the number of tables must be greater than 0", d.t))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	if d.schemaless() && p.Verify != "" && p.Verify != VerifyNone {
+		err := errors.New(fmt.Sprintf("verify is not supported when wType is %s, table names are generated by the server", d.wType))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	if d.wType == Telnet && p.Check {
+		err := errors.New("check is not supported when wType is telnet, table names are generated by the server")
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	return nil
+}
+
+// CheckQueryArgs telnet 写入时每个指标(列)写入服务端生成的一张超级表, 没有可供查询测试读取的表
+func (d *TDengineBackend) CheckQueryArgs() error {
+	if d.wType == Telnet {
+		err := errors.New("query is not supported when wType is telnet, each metric is written to a super table generated by the server")
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	return nil
+}
+
+// SkipAllNull 行协议的一行至少要有一个字段, telnet 协议按列写入, 全部列为 NULL 的记录都无法写入
+func (d *TDengineBackend) SkipAllNull() bool {
+	return d.schemaless()
+}
+
+// Tables multi 模式下由各客户端分摊的子表数, 按设备写入时由设备数决定, 返回 0
+func (d *TDengineBackend) Tables() int {
+	if d.mode == Multi && d.schema.Devices == 0 {
+		return d.t
+	}
+	return 0
+}
+
+// multiTable multi 模式或按设备写入, 一个客户端写多张自动创建的子表
+func (d *TDengineBackend) multiTable() bool {
+	return d.mode == Multi || d.schema.Devices > 0
+}
+
+// schemaless 无模式写入, 超级表和子表由 TDengine 在写入时创建
+func (d *TDengineBackend) schemaless() bool {
+	return d.wType == Line || d.wType == Telnet
+}
+
+func (d *TDengineBackend) tagColumns() []Column {
+	if len(d.schema.Tags) == 0 {
+		return tdDefaultTags
+	}
+	return d.schema.Tags
+}
+
+func (d *TDengineBackend) Connect(T1 int) error {
+	dsn := d.conf.User + ":" + d.conf.Password + "@tcp(" + d.conf.Host + ":" + d.conf.Port + ")/"
+	err, dbList := GetDriverConn(T1, "taosSql", dsn)
+	d.dbList = dbList
+	if err != nil || d.wType == Sql {
+		return err
+	}
+
+	for i := 0; i < T1; i++ {
+		err, conn := openTDNative(d.conf)
+		if err != nil {
+			fmt.Printf("TDengine native connection[%d] failed:%v\n", i+1, err)
+			return err
+		}
+		d.nativeList = append(d.nativeList, conn)
+	}
+	return nil
+}
+
+func (d *TDengineBackend) InitTable(T1 int) error {
+	db := d.dbList[0]
+	dropDatabaseSQL := fmt.Sprintf("DROP DATABASE IF EXISTS %s", Database)
+	_, err := db.Exec(dropDatabaseSQL)
+	if err != nil {
+		fmt.Printf("drop database  %s fail:%v \n", Database, err)
+		return err
+	}
+
+	ctDatabaseSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", Database)
+	_, err = db.Exec(ctDatabaseSQL)
+	if err != nil {
+		fmt.Printf("create database  %s fail:%v \n", Database, err)
+		return err
+	}
+	for _, conn := range d.nativeList {
+		if err = conn.SelectDB(Database); err != nil {
+			fmt.Printf("select database  %s fail:%v \n", Database, err)
+			return err
+		}
+	}
+
+	d.initTags()
+	if d.schemaless() {
+		fmt.Printf("Initialize database completed, tables are created by schemaless write.\n")
+		return nil
+	}
+
+	// 超级表必须有标签列, 表结构中没有定义时使用 (location, groupId)
+	STableName := Database + "." + meters
+	ctSTable := fmt.Sprintf("CREATE STABLE if not exists %s (ts timestamp, %s) TAGS (%s);",
+		STableName, ColumnDefs(TDengine, d.schema.Columns), ColumnDefs(TDengine, d.tagColumns()))
+	_, err = db.Exec(ctSTable)
+	if err != nil {
+		fmt.Printf("create STable  %s fail:%v \n", ctSTable, err)
+		return err
+	}
+
+	// 多表写入时子表在写入时自动创建
+	if !d.multiTable() {
+		tableName := d.tableName(0)
+		ctTableSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s USING %s TAGS (%s);", tableName, STableName, d.tagText[0])
+		_, err = db.Exec(ctTableSQL)
+		if err != nil {
+			fmt.Printf("create table %s fail:%v \n", tableName, err)
+			return err
+		}
+	}
+
+	if d.wType == Stmt {
+		marks := strings.TrimSuffix(strings.Repeat("?,", len(d.schema.Columns)+1), ",")
+		d.stmtSql = "INSERT INTO ? VALUES(" + marks + ")"
+		if d.multiTable() {
+			tagMarks := strings.TrimSuffix(strings.Repeat("?,", len(d.tagColumns())), ",")
+			d.stmtSql = "INSERT INTO ? USING " + meters + " TAGS(" + tagMarks + ") VALUES(" + marks + ")"
+		}
+	}
+
+	fmt.Printf("Initialize database and table completed.\n")
+	return nil
+}
+
+// tableName 第 z 张子表的表名, single 模式为 d0
+func (d *TDengineBackend) tableName(z int) string {
+	if d.multiTable() {
+		return Database + "." + d.conf.TablePrefix + strconv.Itoa(z)
+	}
+	return Database + "." + Table
+}
+
+// RecordTable 多表写入时为记录所属的子表, single 模式为 d0。
+// schemaless 写入时子表名由服务端生成, 不支持校验
+func (d *TDengineBackend) RecordTable(worker int, rec Record) string {
+	return d.tableName(rec.Device)
+}
+
+func (d *TDengineBackend) QueryChecksum(table string, sums bool) (error, TableChecksum) {
+	tsExpr := fmt.Sprintf("cast(ts as bigint) - %d", StartTimestamp)
+	return QueryChecksum(d.dbList[0], table, tsExpr, d.schema.Columns, sums)
+}
+
+// Queried 按设备写入或行协议写入时查询超级表, 读取全部子表, 否则只读取 d0
+func (d *TDengineBackend) Queried(table string) bool {
+	return d.schema.Devices > 0 || d.wType == Line || table == Database+"."+Table
+}
+
+// initTags 生成各子表的标签值, 并按写入方式编码
+func (d *TDengineBackend) initTags() {
+	tableCount := 1
+	if d.schema.Devices > 0 {
+		tableCount = d.schema.Devices
+	} else if d.mode == Multi {
+		tableCount = d.t
+	}
+	d.tags = make([][]interface{}, tableCount)
+	d.tagText = make([]string, tableCount)
+	for z := range d.tags {
+		if len(d.schema.Tags) == 0 {
+			d.tags[z] = []interface{}{"test", int64(z)}
+		} else {
+			d.tags[z] = d.schema.TagValues(z)
+		}
+		switch d.wType {
+		case Line:
+			d.tagText[z] = d.schemalessTags(z, ",", lineEscape)
+		case Telnet:
+			d.tagText[z] = d.schemalessTags(z, " ", nil)
+		default:
+			var values []string
+			for _, v := range d.tags[z] {
+				values = append(values, SqlValue(v))
+			}
+			d.tagText[z] = strings.Join(values, ", ")
+		}
+	}
+}
+
+// schemalessTags 无模式写入的标签 k=v, 以 sep 分隔; 追加 device_id 标签, 使每张子表对应一组不同的标签
+func (d *TDengineBackend) schemalessTags(z int, sep string, escape func(string) string) string {
+	var pairs []string
+	for i, c := range d.tagColumns() {
+		v := FormatValue(d.tags[z][i], "")
+		if escape != nil {
+			v = escape(v)
+		}
+		pairs = append(pairs, c.Name+"="+v)
+	}
+	pairs = append(pairs, DeviceColumn+"="+strconv.Itoa(z))
+	return strings.Join(pairs, sep)
+}
+
+// TruncateTables 多表写入或行协议写入时清空超级表, 即清空全部子表; telnet 写入时每列为一张超级表
+func (d *TDengineBackend) TruncateTables(T1 int) error {
+	tableNames := []string{d.tableName(0)}
+	if d.wType == Telnet {
+		tableNames = nil
+		for _, c := range d.schema.Columns {
+			tableNames = append(tableNames, Database+".`"+c.Name+"`")
+		}
+	} else if d.multiTable() || d.wType == Line {
+		tableNames = []string{Database + "." + meters}
+	}
+	for _, tableName := range tableNames {
+		delSql := fmt.Sprintf("delete from %s", tableName)
+		if err := ExecSql(d.dbList[0], delSql); err != nil {
+			fmt.Printf("truncate table %s fail:%v \n", tableName, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// tableIndex 记录所属子表的序号, single 模式均为 0
+func (d *TDengineBackend) tableIndex(rec Record) int {
+	if d.multiTable() {
+		return rec.Device
+	}
+	return 0
+}
+
+// groupByTable 将记录按子表分组, 子表按第一次出现的顺序排列
+func (d *TDengineBackend) groupByTable(records []Record) ([]int, map[int][]Record) {
+	var tables []int
+	tableRecords := map[int][]Record{}
+	for _, rec := range records {
+		z := d.tableIndex(rec)
+		if _, ok := tableRecords[z]; !ok {
+			tables = append(tables, z)
+		}
+		tableRecords[z] = append(tableRecords[z], rec)
+	}
+	return tables, tableRecords
+}
+
+func (d *TDengineBackend) EncodeBatch(worker int, records []Record) (error, interface{}) {
+	switch d.wType {
+	case Stmt:
+		return nil, d.encodeStmt(records)
+	case Line:
+		return nil, d.encodeLines(records)
+	case Telnet:
+		return nil, d.encodeTelnet(records)
+	}
+
+	//var buffer strings.Builder
+	var buffer bytes.Buffer
+	if !d.multiTable() {
+		buffer.WriteString(fmt.Sprintf("INSERT INTO %s VALUES", d.tableName(0)))
+		for _, rec := range records {
+			d.writeValues(&buffer, rec)
+		}
+		return nil, buffer.String()
+	}
+
+	// 多表写入时一条语句写多张子表, 子表不存在时自动创建:
+	// INSERT INTO d1 USING meters TAGS (...) VALUES (...) (...) d2 USING meters TAGS (...) VALUES (...) ...
+	tables, tableRecords := d.groupByTable(records)
+	buffer.WriteString("INSERT INTO")
+	for _, z := range tables {
+		buffer.WriteString(fmt.Sprintf(" %s USING %s.%s TAGS (%s) VALUES", d.tableName(z), Database, meters, d.tagText[z]))
+		for _, rec := range tableRecords[z] {
+			d.writeValues(&buffer, rec)
+		}
+	}
+	return nil, buffer.String()
+}
+
+func (d *TDengineBackend) writeValues(buffer *bytes.Buffer, rec Record) {
+	buffer.WriteString(" ('" + rec.TsString() + "'")
+	for _, v := range rec.Values {
+		buffer.WriteString(", " + SqlValue(v))
+	}
+	buffer.WriteString(")")
+}
+
+// encodeStmt 将记录按子表分组, 每张子表按列转换为绑定参数, 第一列为 ts
+func (d *TDengineBackend) encodeStmt(records []Record) *tdStmtBatch {
+	batch := &tdStmtBatch{sql: d.stmtSql, columnTypes: param.NewColumnType(len(d.schema.Columns) + 1).AddTimestamp()}
+	for _, c := range d.schema.Columns {
+		addColumnType(batch.columnTypes, c)
+	}
+
+	tables, tableRecords := d.groupByTable(records)
+	for _, z := range tables {
+		recs := tableRecords[z]
+		// 预编译语句在 test 库中执行, 表名不带库名
+		table := tdStmtTable{name: strings.TrimPrefix(d.tableName(z), Database+".")}
+		if d.multiTable() {
+			table.tags = param.NewParam(len(d.tags[z]))
+			for i, c := range d.tagColumns() {
+				addParam(table.tags, c, d.tags[z][i])
+			}
+		}
+
+		ts := param.NewParam(len(recs))
+		for _, rec := range recs {
+			ts.AddTimestamp(time.UnixMilli(rec.Ts), taosCommon.PrecisionMilliSecond)
+		}
+		table.columns = append(table.columns, ts)
+		for i, c := range d.schema.Columns {
+			p := param.NewParam(len(recs))
+			for _, rec := range recs {
+				addParam(p, c, rec.Values[i])
+			}
+			table.columns = append(table.columns, p)
+		}
+		batch.tables = append(batch.tables, table)
+	}
+	return batch
+}
+
+func addColumnType(ct *param.ColumnType, c Column) {
+	switch c.Type {
+	case TypeInt:
+		ct.AddInt()
+	case TypeBigint:
+		ct.AddBigint()
+	case TypeFloat:
+		ct.AddFloat()
+	case TypeDouble:
+		ct.AddDouble()
+	case TypeBool:
+		ct.AddBool()
+	default:
+		ct.AddBinary(c.Length)
+	}
+}
+
+// addParam 按列类型追加一个绑定参数, v 为 nil 时追加 NULL
+func addParam(p *param.Param, c Column, v interface{}) {
+	if v == nil {
+		p.AddNull()
+		return
+	}
+	switch c.Type {
+	case TypeInt:
+		p.AddInt(int(v.(int64)))
+	case TypeBigint:
+		p.AddBigint(int(v.(int64)))
+	case TypeFloat:
+		p.AddFloat(float32(v.(float64)))
+	case TypeDouble:
+		p.AddDouble(v.(float64))
+	case TypeBool:
+		p.AddBool(v.(bool))
+	default:
+		p.AddBinary([]byte(v.(string)))
+	}
+}
+
+// encodeLines 编码为 InfluxDB 行协议, 如 meters,location=test,groupId=0,device_id=0 current=1.5f32,voltage=3i32 1626006833639。
+// NULL 列不写入, 全部列为 NULL 的记录跳过
+func (d *TDengineBackend) encodeLines(records []Record) []string {
+	lines := make([]string, 0, len(records))
+	for _, rec := range records {
+		var fields []string
+		for i, c := range d.schema.Columns {
+			if rec.Values[i] != nil {
+				fields = append(fields, c.Name+"="+schemalessValue(c, rec.Values[i]))
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		lines = append(lines, meters+","+d.tagText[d.tableIndex(rec)]+" "+strings.Join(fields, ",")+" "+strconv.FormatInt(rec.Ts, 10))
+	}
+	return lines
+}
+
+// encodeTelnet 编码为 OpenTSDB telnet 协议, 每列一个指标, 如 current 1626006833639 1.5f32 location=test groupId=0 device_id=0
+func (d *TDengineBackend) encodeTelnet(records []Record) []string {
+	lines := make([]string, 0, len(records)*len(d.schema.Columns))
+	for _, rec := range records {
+		ts := strconv.FormatInt(rec.Ts, 10)
+		tags := d.tagText[d.tableIndex(rec)]
+		for i, c := range d.schema.Columns {
+			if rec.Values[i] != nil {
+				lines = append(lines, c.Name+" "+ts+" "+schemalessValue(c, rec.Values[i])+" "+tags)
+			}
+		}
+	}
+	return lines
+}
+
+// schemalessValue 无模式写入的字段值, 数值加类型后缀, 字符串加双引号
+func schemalessValue(c Column, v interface{}) string {
+	switch c.Type {
+	case TypeInt:
+		return FormatValue(v, "") + "i32"
+	case TypeBigint:
+		return FormatValue(v, "") + "i64"
+	case TypeFloat:
+		return FormatValue(v, "") + "f32"
+	case TypeString:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v.(string)) + `"`
+	default:
+		return FormatValue(v, "")
+	}
+}
+
+// lineEscape 转义行协议标签值中的逗号、等号和空格
+func lineEscape(s string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(s)
+}
+
+func (d *TDengineBackend) WriteBatch(worker int, batch interface{}) error {
+	switch d.wType {
+	case Stmt:
+		return d.nativeList[worker].InsertStmt(batch.(*tdStmtBatch))
+	case Line:
+		return d.nativeList[worker].InsertLines(batch.([]string))
+	case Telnet:
+		return d.nativeList[worker].InsertTelnet(batch.([]string))
+	}
+	return ExecSql(d.dbList[worker], batch.(string))
+}
+
+func (d *TDengineBackend) Flush(worker int) error {
+	return nil
+}
+
+func (d *TDengineBackend) Queries() []string {
+	return []string{QueryNameCount, QueryNameSelect, QueryNamePoint, QueryNameAvg, QueryNameSum, QueryNameMax, QueryNameMin, QueryNameTimeWindow, QueryNameLatest}
+}
+
+func (d *TDengineBackend) RunQuery(worker int, name string) (error, float64) {
+	// 按设备写入或行协议写入时查询超级表
+	tableName := Database + "." + Table
+	if d.schema.Devices > 0 || d.wType == Line {
+		tableName = Database + "." + meters
+	}
+	column := d.schema.AggColumn()
+	var timeWindowSql string
+	if column != "" {
+		timeWindowSql = fmt.Sprintf("select _wstart, _wend, max(`%s`), min(`%s`) from %s interval(60m) sliding(60m)", column, column, tableName)
+	}
+	return RunSqlQuery(d.dbList[worker], name, tableName, d.conf.PointQueryTsCondition, column, timeWindowSql)
+}
+
+func (d *TDengineBackend) Close() {
+	for _, conn := range d.dbList {
+		conn.Close()
+	}
+	for _, conn := range d.nativeList {
+		conn.Close()
+	}
+}
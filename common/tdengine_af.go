@@ -0,0 +1,82 @@
+//go:build taos
+
+package common
+
+import (
+	"strconv"
+
+	"github.com/taosdata/driver-go/v3/af"
+	"github.com/taosdata/driver-go/v3/af/insertstmt"
+)
+
+// afConn 基于 af 包(cgo)的原生连接, 参数绑定写入时复用同一条预编译语句
+type afConn struct {
+	conn    *af.Connector
+	stmt    *insertstmt.InsertStmt
+	stmtSql string
+}
+
+func openTDNative(conf *DBConfig) (error, tdNative) {
+	port, err := strconv.Atoi(conf.Port)
+	if err != nil {
+		return err, nil
+	}
+	conn, err := af.Open(conf.Host, conf.User, conf.Password, "", port)
+	if err != nil {
+		return err, nil
+	}
+	return nil, &afConn{conn: conn}
+}
+
+func (c *afConn) SelectDB(db string) error {
+	return c.conn.SelectDB(db)
+}
+
+func (c *afConn) InsertLines(lines []string) error {
+	return c.conn.InfluxDBInsertLines(lines, "ms")
+}
+
+func (c *afConn) InsertTelnet(lines []string) error {
+	return c.conn.OpenTSDBInsertTelnetLines(lines)
+}
+
+func (c *afConn) InsertStmt(batch *tdStmtBatch) error {
+	if c.stmt == nil || c.stmtSql != batch.sql {
+		if c.stmt != nil {
+			c.stmt.Close()
+		}
+		c.stmt = c.conn.InsertStmt()
+		if err := c.stmt.Prepare(batch.sql); err != nil {
+			c.stmt.Close()
+			c.stmt = nil
+			return err
+		}
+		c.stmtSql = batch.sql
+	}
+
+	for _, table := range batch.tables {
+		var err error
+		if table.tags != nil {
+			err = c.stmt.SetTableNameWithTags(table.name, table.tags)
+		} else {
+			err = c.stmt.SetTableName(table.name)
+		}
+		if err != nil {
+			return err
+		}
+		if err = c.stmt.BindParam(table.columns, batch.columnTypes); err != nil {
+			return err
+		}
+		if err = c.stmt.AddBatch(); err != nil {
+			return err
+		}
+	}
+	return c.stmt.Execute()
+}
+
+func (c *afConn) Close() {
+	if c.stmt != nil {
+		c.stmt.Close()
+	}
+	c.conn.Close()
+}
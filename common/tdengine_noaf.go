@@ -0,0 +1,9 @@
+//go:build !taos
+
+package common
+
+import "errors"
+
+func openTDNative(conf *DBConfig) (error, tdNative) {
+	return errors.New("TDengine native interface is not compiled in, rebuild with '-tags taos'"), nil
+}
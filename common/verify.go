@@ -0,0 +1,204 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// 写入后的校验方式
+const (
+	VerifyNone     = "none"
+	VerifyCount    = "count"    // 按表比较记录数
+	VerifyChecksum = "checksum" // 同时比较 ts 与各数值列之和, 与写入顺序无关
+)
+
+// TsChecksumColumn 校验和中 ts 的名称, 值为相对 StartTimestamp 的毫秒数
+const TsChecksumColumn = "ts"
+
+// TableChecksum 一张表的记录数, 以及 ts 与各数值列之和(NULL 不计)。
+// 数据库不支持计算某一列之和时 Sums 中没有该列, 校验时跳过
+type TableChecksum struct {
+	Rows int64
+	Sums map[string]float64
+	abs  map[string]float64 // 客户端计算的各列绝对值之和, 用于确定比较的容差
+}
+
+// VerifyBackend 由支持写入后校验数据的数据库实现
+type VerifyBackend interface {
+	// RecordTable 返回第 worker 个客户端写入的记录 rec 所在的表
+	RecordTable(worker int, rec Record) string
+	// QueryChecksum 查询表 table 的记录数, sums 为 true 时同时查询 ts 与各数值列之和
+	QueryChecksum(table string, sums bool) (error, TableChecksum)
+}
+
+// CheckVerify 校验写入后的校验方式, 数据库需要实现 VerifyBackend
+func CheckVerify(b Backend, verify string) error {
+	switch verify {
+	case "", VerifyNone:
+		return nil
+	case VerifyCount, VerifyChecksum:
+		if _, ok := b.(VerifyBackend); ok {
+			return nil
+		}
+		err := errors.New(fmt.Sprintf("%s does not support verify", b.Name()))
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	err := errors.New(fmt.Sprintf("unrecognized verify value:%s, required to be none|count|checksum", verify))
+	fmt.Printf("%v\n", err)
+	return err
+}
+
+// checksums 按表累加的期望结果
+type checksums map[string]*TableChecksum
+
+func (c checksums) table(name string) *TableChecksum {
+	t := c[name]
+	if t == nil {
+		t = &TableChecksum{Sums: map[string]float64{}, abs: map[string]float64{}}
+		c[name] = t
+	}
+	return t
+}
+
+// add 累加一条记录, sums 为 false 时只计数
+func (c checksums) add(table string, rec Record, schema *Schema, sums bool) {
+	t := c.table(table)
+	t.Rows++
+	if !sums {
+		return
+	}
+	t.addValue(TsChecksumColumn, float64(rec.Ts-StartTimestamp))
+	for i, col := range schema.Columns {
+		switch v := rec.Values[i].(type) {
+		case int64:
+			t.addValue(col.Name, float64(v))
+		case float64:
+			t.addValue(col.Name, v)
+		}
+	}
+}
+
+func (t *TableChecksum) addValue(name string, v float64) {
+	t.Sums[name] += v
+	t.abs[name] += math.Abs(v)
+}
+
+func (c checksums) merge(o checksums) {
+	for name, ot := range o {
+		t := c.table(name)
+		t.Rows += ot.Rows
+		for k, v := range ot.Sums {
+			t.Sums[k] += v
+		}
+		for k, v := range ot.abs {
+			t.abs[k] += v
+		}
+	}
+}
+
+// VerifyResult 一轮写入后的校验结果
+type VerifyResult struct {
+	Mode       string        `json:"mode"`
+	Tables     []TableVerify `json:"tables"`
+	Lost       int64         `json:"lost"`       // 全部表中缺少的记录数
+	Duplicated int64         `json:"duplicated"` // 全部表中多出的记录数, 如超时后重试实际已写入的请求
+}
+
+// TableVerify 一张表的校验结果
+type TableVerify struct {
+	Table    string   `json:"table"`
+	Expected int64    `json:"expected"` // 写入成功的记录数
+	Actual   int64    `json:"actual"`
+	Mismatch []string `json:"mismatch,omitempty"` // 之和不一致的列
+}
+
+// checksumMatch 比较期望与实际的列之和: 整数列与 ts 允许浮点累加的误差,
+// 浮点列还允许数据库以 float 存储的精度损失
+func checksumMatch(schema *Schema, name string, expected, actual, abs float64) bool {
+	tolerance := 1e-12*abs + 0.5
+	for _, col := range schema.Columns {
+		if col.Name == name && (col.Type == TypeFloat || col.Type == TypeDouble) {
+			tolerance = 1e-6*abs + 1e-6
+		}
+	}
+	return math.Abs(expected-actual) <= tolerance
+}
+
+// verifyTables 查询 expected 中每张表的记录数(及校验和)并与写入成功的记录比较
+func verifyTables(b Backend, p WriteParams, expected checksums) (error, *VerifyResult) {
+	vb := b.(VerifyBackend)
+	sums := p.Verify == VerifyChecksum
+	result := &VerifyResult{Mode: p.Verify}
+	var names []string
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		want := expected[name]
+		err, got := vb.QueryChecksum(name, sums)
+		if err != nil {
+			fmt.Printf("verify table %s fail:%v\n", name, err)
+			return err, result
+		}
+		tv := TableVerify{Table: name, Expected: want.Rows, Actual: got.Rows}
+		if sums {
+			var cols []string
+			for col := range got.Sums {
+				cols = append(cols, col)
+			}
+			sort.Strings(cols)
+			for _, col := range cols {
+				if !checksumMatch(p.Gen.Schema, col, want.Sums[col], got.Sums[col], want.abs[col]) {
+					tv.Mismatch = append(tv.Mismatch, col)
+				}
+			}
+		}
+		if got.Rows < want.Rows {
+			result.Lost += want.Rows - got.Rows
+		} else {
+			result.Duplicated += got.Rows - want.Rows
+		}
+		result.Tables = append(result.Tables, tv)
+	}
+	return nil, result
+}
+
+// PrintVerify 输出每张表的校验结果, 不一致时返回数据丢失或重复的错误
+func PrintVerify(name string, v *VerifyResult) error {
+	var failed []string
+	for _, t := range v.Tables {
+		state := "ok"
+		switch {
+		case t.Actual < t.Expected:
+			state = fmt.Sprintf("%d records lost", t.Expected-t.Actual)
+		case t.Actual > t.Expected:
+			state = fmt.Sprintf("%d records duplicated", t.Actual-t.Expected)
+		}
+		if len(t.Mismatch) > 0 {
+			if state == "ok" {
+				state = ""
+			} else {
+				state += ", "
+			}
+			state += "checksum mismatch on " + strings.Join(t.Mismatch, ",")
+		}
+		fmt.Printf("verify %s: expected %d records, found %d, %s\n", t.Table, t.Expected, t.Actual, state)
+		if state != "ok" {
+			failed = append(failed, t.Table+": "+state)
+		}
+	}
+	if len(failed) == 0 {
+		fmt.Printf("verify %s: %d tables ok\n", v.Mode, len(v.Tables))
+		return nil
+	}
+	err := errors.New(fmt.Sprintf("%s data verification fail, %d records lost, %d records duplicated (%s)",
+		name, v.Lost, v.Duplicated, strings.Join(failed, "; ")))
+	fmt.Printf("%v\n", err)
+	return err
+}
@@ -1,13 +0,0 @@
-module performance_testing/influxDB/influxdb-write
-
-go 1.21.3
-
-require github.com/influxdata/influxdb1-client v0.0.0-20220302092344-a9ab5670611c
-
-require (
-	github.com/astaxie/beego v1.12.3 // indirect
-	github.com/shiena/ansicolor v0.0.0-20151119151921-a422bbe96644 // indirect
-	performance_testing/common v0.0.0-00010101000000-000000000000 // indirect
-)
-
-replace performance_testing/common => ../../common